@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	"scholar-bot/apihandlers"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+var (
+	ownersMu sync.Mutex
+	// botOwners caches the IDs of the users owning the bot's application.
+	botOwners []string
+)
+
+// BotOwnerHelper reports whether the user owns the bot's application or is
+// on the team owning it.
+func BotOwnerHelper(botSession *discordgo.Session, userID string) (bool, error) {
+	ownersMu.Lock()
+	defer ownersMu.Unlock()
+
+	if botOwners == nil {
+		application, err := botSession.Application("@me")
+		if err != nil {
+			return false, err
+		}
+		owners := []string{}
+		if application.Owner != nil {
+			owners = append(owners, application.Owner.ID)
+		}
+		if application.Team != nil {
+			for _, member := range application.Team.Members {
+				owners = append(owners, member.User.ID)
+			}
+		}
+		botOwners = owners
+	}
+	return slices.Contains(botOwners, userID), nil
+}
+
+func AdminCommandHandler(ctx context.Context, botSession *discordgo.Session, botInteraction *discordgo.InteractionCreate) {
+	owner, err := BotOwnerHelper(botSession, InteractionUserHelper(botInteraction).ID)
+	if err != nil {
+		apihandlers.Logger(ctx).Error("cannot fetch the bot owners", "err", err)
+		EphemeralResponseHelper(ctx, botSession, botInteraction, "Cannot check who owns the bot, please try again later")
+		return
+	}
+	if !owner {
+		EphemeralResponseHelper(ctx, botSession, botInteraction, "Only the bot's owner can use /admin")
+		return
+	}
+
+	switch botInteraction.ApplicationCommandData().Options[0].Name {
+	case "reload":
+		result, err := ReloadConfigHelper(botSession)
+		if err != nil {
+			apihandlers.Logger(ctx).Error("cannot reload configuration", "err", err)
+			EphemeralResponseHelper(ctx, botSession, botInteraction, fmt.Sprintf("Reload failed:\n```\n%v\n```", err))
+			return
+		}
+		EphemeralResponseHelper(ctx, botSession, botInteraction, ReloadMessageHelper(result))
+	}
+}
+
+func ReloadMessageHelper(result ReloadResult) string {
+	lines := []string{"Configuration reloaded"}
+	if len(result.Changed) == 0 {
+		lines = append(lines, "Nothing changed")
+	} else {
+		lines = append(lines, "Changed: "+strings.Join(result.Changed, ", "))
+	}
+	if len(result.Restart) != 0 {
+		lines = append(lines, "Only applied after a restart: "+strings.Join(result.Restart, ", "))
+	}
+	if result.CommandsSynced {
+		lines = append(lines, "Commands were updated on Discord")
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,401 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"scholar-bot/apihandlers"
+	"scholar-bot/feeds"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+const (
+	// alertSeenLimit bounds how many posted identifiers an alert remembers
+	alertSeenLimit = 500
+	// alertPendingLimit bounds how many studies an alert keeps for the digest
+	alertPendingLimit = 100
+	// alertRecentLimit bounds how many studies an alert's feed shows
+	alertRecentLimit = 50
+	// alertRetryDelay is how soon a failed alert is run again
+	alertRetryDelay = 15 * time.Minute
+	// alertTick is how often the scheduler looks for due alerts
+	alertTick = time.Minute
+	// alertChannelGone is why alerts that can't post in their channel are
+	// paused
+	alertChannelGone = "the bot cannot post in the channel"
+)
+
+var alertIntervalChoices = []*discordgo.ApplicationCommandOptionChoice{
+	{Name: "Hourly", Value: 1},
+	{Name: "Every 6 hours", Value: 6},
+	{Name: "Daily", Value: 24},
+	{Name: "Weekly", Value: 168},
+}
+
+var errAlertNotFound = errors.New("alert not found")
+
+// RunAlertScheduler runs due alerts and digests until ctx is cancelled.
+func RunAlertScheduler(ctx context.Context, botSession *discordgo.Session, stop <-chan struct{}) {
+	ticker := time.NewTicker(alertTick)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-stop:
+			return
+		case <-ticker.C:
+			records, err := alerts.List("")
+			if err != nil {
+				slog.Error("cannot load alerts", "err", err)
+				continue
+			}
+			now := time.Now()
+			for _, record := range records {
+				if record.Value.Paused || record.Value.NextRun.After(now) {
+					continue
+				}
+				if DrainingHelper() {
+					// Left for the next start, as NextRun wasn't moved
+					return
+				}
+				RunAlertHelper(ctx, botSession, record.Key, record.Value)
+			}
+			RunDueDigestsHelper(botSession, now)
+		}
+	}
+}
+
+// studyKeyHelper identifies a study for de-duplication, falling back to
+// its URL when the source gave no identifier.
+func studyKeyHelper(study apihandlers.StudyStruct) string {
+	if identifier := study.Identifier(); identifier != "" {
+		return identifier
+	}
+	return study.Url
+}
+
+// newAlertStudiesHelper runs the alert's search and returns the studies it
+// hasn't seen yet.
+func newAlertStudiesHelper(ctx context.Context, alert Alert) ([]apihandlers.StudyStruct, error) {
+	// Entrez dates only have day precision, the seen list removes repeats
+	since := alert.LastRun.AddDate(0, 0, -1)
+	studySlice, err := apihandlers.QueryRecent(ctx, alert.Source, alert.Query, since)
+	if errors.Is(err, apihandlers.ErrNoResults) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool, len(alert.Seen))
+	for _, key := range alert.Seen {
+		seen[key] = true
+	}
+	var studies []apihandlers.StudyStruct
+	for _, study := range *studySlice {
+		if !seen[studyKeyHelper(study)] {
+			studies = append(studies, study)
+		}
+	}
+	return studies, nil
+}
+
+// markAlertSeenHelper records a run of the alert and the studies it found,
+// keeping them for the digest when pending is set.
+func markAlertSeenHelper(key string, studies []apihandlers.StudyStruct, runAt time.Time, pending bool) error {
+	return alerts.Update(key, func(alert *Alert, exists bool) (bool, error) {
+		if !exists {
+			return false, errAlertNotFound
+		}
+		for _, study := range studies {
+			alert.Seen = append(alert.Seen, studyKeyHelper(study))
+		}
+		for _, study := range studies {
+			alert.Recent = append(alert.Recent, feeds.Item{Study: study, FoundAt: runAt})
+		}
+		if len(alert.Recent) > alertRecentLimit {
+			alert.Recent = alert.Recent[len(alert.Recent)-alertRecentLimit:]
+		}
+		if pending {
+			alert.Pending = append(alert.Pending, studies...)
+			if len(alert.Pending) > alertPendingLimit {
+				alert.Pending = alert.Pending[len(alert.Pending)-alertPendingLimit:]
+			}
+		}
+		if len(alert.Seen) > alertSeenLimit {
+			alert.Seen = alert.Seen[len(alert.Seen)-alertSeenLimit:]
+		}
+		alert.LastRun = runAt
+		alert.NextRun = runAt.Add(alert.Interval)
+		return false, nil
+	})
+}
+
+// RunAlertHelper runs one alert and posts its new papers to its channel.
+func RunAlertHelper(ctx context.Context, botSession *discordgo.Session, key string, alert Alert) {
+	ctx = apihandlers.ContextWithLogger(ctx, slog.With("alert", key))
+	ctx = apihandlers.ContextWithQueue(ctx, alert.GuildID, nil)
+	runAt := time.Now()
+	studies, err := newAlertStudiesHelper(ctx, alert)
+	if err != nil {
+		apihandlers.Logger(ctx).Error("cannot run alert", "err", err)
+		retryAlertHelper(key, runAt, "")
+		return
+	}
+
+	// Guilds with a digest get their papers batched once a week instead
+	settings, _, err := guildSettings.Get(alert.GuildID)
+	if err != nil {
+		apihandlers.Logger(ctx).Error("cannot load guild settings", "guild", alert.GuildID, "err", err)
+	}
+	digest := settings.Digest != nil
+
+	if len(studies) != 0 && !digest {
+		_, err := botSession.ChannelMessageSendComplex(alert.ChannelID, &discordgo.MessageSend{
+			Content: AlertMessageHelper(alert, studies),
+		})
+		if ChannelGoneHelper(err) {
+			apihandlers.Logger(ctx).Warn("cannot post in the alert's channel, pausing it", "channel", alert.ChannelID, "err", err)
+			retryAlertHelper(key, runAt, alertChannelGone)
+			return
+		}
+		if err != nil {
+			// Try again later rather than losing the papers
+			apihandlers.Logger(ctx).Error("cannot post alert", "err", err)
+			retryAlertHelper(key, runAt, "")
+			return
+		}
+	}
+
+	if err := markAlertSeenHelper(key, studies, runAt, digest); err != nil && !errors.Is(err, errAlertNotFound) {
+		apihandlers.Logger(ctx).Error("cannot save alert", "err", err)
+	}
+}
+
+// retryAlertHelper runs the alert again after alertRetryDelay, or pauses it
+// with the reason when there is one.
+func retryAlertHelper(key string, runAt time.Time, pauseReason string) {
+	err := alerts.Update(key, func(alert *Alert, exists bool) (bool, error) {
+		if !exists {
+			return false, errAlertNotFound
+		}
+		alert.NextRun = runAt.Add(min(alert.Interval, alertRetryDelay))
+		if pauseReason != "" {
+			alert.Paused = true
+			alert.PausedReason = pauseReason
+		}
+		return false, nil
+	})
+	if err != nil && !errors.Is(err, errAlertNotFound) {
+		slog.Error("cannot save alert", "alert", key, "err", err)
+	}
+}
+
+// ChannelGoneHelper reports whether a Discord error means the bot can no
+// longer post in a channel, as it was deleted or the bot lost access.
+func ChannelGoneHelper(err error) bool {
+	var restErr *discordgo.RESTError
+	if !errors.As(err, &restErr) {
+		return false
+	}
+	if restErr.Message != nil {
+		switch restErr.Message.Code {
+		case discordgo.ErrCodeUnknownChannel, discordgo.ErrCodeMissingAccess, discordgo.ErrCodeMissingPermissions:
+			return true
+		}
+	}
+	return restErr.Response != nil &&
+		(restErr.Response.StatusCode == http.StatusForbidden || restErr.Response.StatusCode == http.StatusNotFound)
+}
+
+func AlertMessageHelper(alert Alert, studies []apihandlers.StudyStruct) string {
+	message := fmt.Sprintf(
+		"🔔 %d new papers on %s for `%s`\n",
+		len(studies), apihandlers.Sources[alert.Source].Name(), alert.Query,
+	)
+	for i, study := range studies {
+		line := StudyListHelper([]apihandlers.StudyStruct{study})
+		if len(message)+len(line) > 1900 {
+			message += fmt.Sprintf("… and %d more", len(studies)-i)
+			break
+		}
+		message += line
+	}
+	return message
+}
+
+// seedAlertHelper marks what the search currently finds as seen, so a new
+// alert only posts papers that appear after it was created.
+func seedAlertHelper(ctx context.Context, key string, alert Alert) {
+	ctx = apihandlers.ContextWithLogger(ctx, apihandlers.Logger(ctx).With("alert", key))
+	studies, err := newAlertStudiesHelper(ctx, alert)
+	if err != nil {
+		apihandlers.Logger(ctx).Error("cannot seed alert", "err", err)
+		return
+	}
+	if err := markAlertSeenHelper(key, studies, alert.LastRun, false); err != nil {
+		apihandlers.Logger(ctx).Error("cannot seed alert", "err", err)
+	}
+}
+
+func newAlertIdHelper() string {
+	id := make([]byte, 3)
+	rand.Read(id)
+	return hex.EncodeToString(id)
+}
+
+func AlertCommandHandler(ctx context.Context, botSession *discordgo.Session, botInteraction *discordgo.InteractionCreate) {
+	subcommand := botInteraction.ApplicationCommandData().Options[0]
+	optionMap := make(
+		map[string]*discordgo.ApplicationCommandInteractionDataOption,
+		len(subcommand.Options),
+	)
+	for _, opt := range subcommand.Options {
+		optionMap[opt.Name] = opt
+	}
+
+	if botInteraction.GuildID == "" {
+		EphemeralResponseHelper(ctx, botSession, botInteraction, "Alerts can only be created in servers")
+		return
+	}
+
+	switch subcommand.Name {
+	case "create":
+		alert := Alert{
+			ID:        newAlertIdHelper(),
+			GuildID:   botInteraction.GuildID,
+			ChannelID: botInteraction.ChannelID,
+			CreatedBy: InteractionUserHelper(botInteraction).ID,
+			Query:     optionMap["query"].StringValue(),
+			Source:    GuildSettingsHelper(botInteraction.GuildID).DefaultSource,
+			Interval:  24 * time.Hour,
+			LastRun:   time.Now(),
+			FeedToken: newFeedToken(),
+		}
+		if source, ok := optionMap["source"]; ok {
+			alert.Source = source.StringValue()
+		}
+		if channel, ok := optionMap["channel"]; ok {
+			alert.ChannelID = channel.ChannelValue(botSession).ID
+		}
+		if interval, ok := optionMap["interval"]; ok {
+			alert.Interval = time.Duration(interval.IntValue()) * time.Hour
+		}
+		alert.NextRun = alert.LastRun.Add(alert.Interval)
+
+		key := alertKey(alert.GuildID, alert.ID)
+		if err := alerts.Put(key, alert); err != nil {
+			apihandlers.Logger(ctx).Error("cannot create alert", "alert", key, "err", err)
+			EphemeralResponseHelper(ctx, botSession, botInteraction, "An error happened when creating the alert")
+			return
+		}
+		GoHelper(ctx, func(ctx context.Context) {
+			seedAlertHelper(ctx, key, alert)
+		})
+
+		RespondHelper(ctx, botSession, botInteraction,
+			&discordgo.InteractionResponse{
+				Type: discordgo.InteractionResponseChannelMessageWithSource,
+				Data: &discordgo.InteractionResponseData{
+					Content: fmt.Sprintf(
+						"Created alert `%s`: new %s papers for `%s` will be posted in <#%s> every %s",
+						alert.ID, apihandlers.Sources[alert.Source].Name(), alert.Query, alert.ChannelID, alert.Interval,
+					),
+				},
+			})
+
+	case "list":
+		records, err := alerts.List(alertKey(botInteraction.GuildID, ""))
+		if err != nil {
+			apihandlers.Logger(ctx).Error("cannot list alerts", "err", err)
+			EphemeralResponseHelper(ctx, botSession, botInteraction, "An error happened when listing the alerts")
+			return
+		}
+		if len(records) == 0 {
+			EphemeralResponseHelper(ctx, botSession, botInteraction, "There are no alerts, create one with `/alert create`")
+			return
+		}
+		var alertList string
+		for _, record := range records {
+			alert := record.Value
+			status := fmt.Sprintf("next run <t:%d:R>", alert.NextRun.Unix())
+			if alert.Paused {
+				status = "paused"
+				if alert.PausedReason != "" {
+					status += " as " + alert.PausedReason
+				}
+			}
+			alertList += fmt.Sprintf(
+				"`%s` %s `%s` in <#%s> every %s, %s\n",
+				alert.ID, apihandlers.Sources[alert.Source].Name(), alert.Query, alert.ChannelID, alert.Interval, status,
+			)
+		}
+		EphemeralResponseHelper(ctx, botSession, botInteraction, alertList)
+
+	case "feed":
+		alertID := strings.TrimSpace(optionMap["id"].StringValue())
+		alert, ok, err := alerts.Get(alertKey(botInteraction.GuildID, alertID))
+		if err != nil || !ok {
+			EphemeralResponseHelper(ctx, botSession, botInteraction, fmt.Sprintf("There is no alert `%s`", alertID))
+			return
+		}
+		if feedBaseUrl == "" {
+			EphemeralResponseHelper(ctx, botSession, botInteraction, "Feeds are not enabled on this bot")
+			return
+		}
+		EphemeralResponseHelper(
+			ctx,
+			botSession,
+			botInteraction,
+			fmt.Sprintf("Atom feed of alert `%s`: <%s>", alert.ID, FeedUrlHelper(alert)),
+		)
+
+	case "pause", "resume", "delete":
+		alertID := strings.TrimSpace(optionMap["id"].StringValue())
+		key := alertKey(botInteraction.GuildID, alertID)
+		err := alerts.Update(key, func(alert *Alert, exists bool) (bool, error) {
+			if !exists {
+				return false, errAlertNotFound
+			}
+			switch subcommand.Name {
+			case "pause":
+				alert.Paused = true
+			case "resume":
+				alert.Paused = false
+				alert.PausedReason = ""
+				if alert.NextRun.Before(time.Now()) {
+					alert.NextRun = time.Now()
+				}
+			case "delete":
+				return true, nil
+			}
+			return false, nil
+		})
+		if errors.Is(err, errAlertNotFound) {
+			EphemeralResponseHelper(ctx, botSession, botInteraction, fmt.Sprintf("There is no alert `%s`", alertID))
+			return
+		}
+		if err != nil {
+			apihandlers.Logger(ctx).Error("cannot update alert", "alert", key, "err", err)
+			EphemeralResponseHelper(ctx, botSession, botInteraction, "An error happened when updating the alert")
+			return
+		}
+		EphemeralResponseHelper(
+			ctx,
+			botSession,
+			botInteraction,
+			fmt.Sprintf("Alert `%s` %s", alertID, map[string]string{
+				"pause": "paused", "resume": "resumed", "delete": "deleted",
+			}[subcommand.Name]),
+		)
+	}
+}
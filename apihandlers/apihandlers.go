@@ -1,31 +1,116 @@
 package apihandlers
 
 import (
+	"context"
 	"encoding/json"
 	"encoding/xml"
+	"errors"
 	"fmt"
-	"log"
 	"net/http"
 	"net/url"
+	"regexp"
 	"strings"
+	"time"
+	"unicode"
 
 	"github.com/PuerkitoBio/goquery"
 )
 
+// ErrNoResults is returned when a source answered correctly but had no
+// studies matching the query.
+var ErrNoResults = errors.New("no studies found")
+
+// Author is one author of a study, split into name parts when the source
+// provides them.
+type Author struct {
+	LastName string
+	ForeName string
+	Initials string
+}
+
+// InitialLetters returns the author's initials, working them out from the
+// fore name when the source didn't provide them.
+func (a Author) InitialLetters() []string {
+	var letters []string
+	if a.Initials != "" {
+		for _, r := range a.Initials {
+			if unicode.IsLetter(r) {
+				letters = append(letters, string(r))
+			}
+		}
+		return letters
+	}
+	for _, name := range strings.FieldsFunc(a.ForeName, func(r rune) bool {
+		return r == ' ' || r == '-' || r == '.'
+	}) {
+		letters = append(letters, string([]rune(name)[0]))
+	}
+	return letters
+}
+
+// GivenName returns the fore name, or dotted initials when it is unknown.
+func (a Author) GivenName() string {
+	if a.ForeName != "" {
+		return a.ForeName
+	}
+	letters := a.InitialLetters()
+	if len(letters) == 0 {
+		return ""
+	}
+	return strings.Join(letters, ". ") + "."
+}
+
 type StudyStruct struct {
 	Title    string
 	Url      string
 	Authors  string
 	Abstract string
+	Source   string
+
+	AuthorList    []Author
+	Venue         string
+	JournalAbbrev string
+	Year          string
+	Month         string
+	Volume        string
+	Issue         string
+	Pages         string
+	Doi           string
+	Pmid          string
+	Pmcid         string
+
+	Publisher   string
+	CitedBy     int
+	CitedByUrl  string
+	RelatedUrl  string
+	Versions    int
+	VersionsUrl string
+	PdfUrl      string
+	CiteId      string
 }
 
-func QueryFirstGs(query string, minYear string) (*StudyStruct, bool) {
-	// Define the URL of the Google Scholar search page
-	urlQuery := fmt.Sprintf(
-		"https://scholar.google.com/scholar?hl=en&q=%s&as_ylo=%s",
-		url.QueryEscape(query), minYear,
-	)
-	fmt.Println(urlQuery)
+var pageRangeRegex = regexp.MustCompile(`^\s*(\w+)\s*-\s*(\w+)\s*$`)
+
+// PageRange splits a MEDLINE page range such as "123-9" into its first and
+// last page, expanding the abbreviated last page.
+func (study StudyStruct) PageRange() (string, string) {
+	match := pageRangeRegex.FindStringSubmatch(study.Pages)
+	if match == nil {
+		return strings.TrimSpace(study.Pages), ""
+	}
+	start, end := match[1], match[2]
+	if len(end) < len(start) {
+		end = start[:len(start)-len(end)] + end
+	}
+	return start, end
+}
+
+// gsGet sends a GET request to Google Scholar, refusing to while Scholar is
+// cooling down and starting a cool-down when the response is a block.
+func gsGet(ctx context.Context, urlQuery string) (*http.Response, error) {
+	if remaining := ScholarCoolDownRemaining(); remaining > 0 {
+		return nil, fmt.Errorf("%w (%s left)", ErrScholarCoolDown, remaining.Round(time.Second))
+	}
 
 	// Define a User-Agent header
 	headers := map[string]string{
@@ -33,130 +118,157 @@ func QueryFirstGs(query string, minYear string) (*StudyStruct, bool) {
 	}
 
 	// Send a GET request to the URL with the User-Agent header
-	client := &http.Client{}
-	req, err := http.NewRequest("GET", urlQuery, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", urlQuery, nil)
 	if err != nil {
-		log.Printf("error getting the request %v", err)
-		return nil, false
+		return nil, fmt.Errorf("error getting the request: %w", err)
 	}
 	for key, value := range headers {
 		req.Header.Add(key, value)
 	}
 
 	// Check if the request was successful (status code 200)
-	resp, err := client.Do(req)
+	resp, err := sendRequest(ctx, "scholar", req)
 	if err != nil {
-		log.Printf("error in executing the request %v", err)
-		return nil, false
+		return nil, err
 	}
-	defer resp.Body.Close()
-	log.Println(resp.StatusCode)
-
-	if resp.StatusCode == 200 {
-		// Parse the HTML content of the page using goquery
-		doc, err := goquery.NewDocumentFromReader(resp.Body)
-		if err != nil {
-			log.Printf("error in parsing html %v", err)
-			return nil, false
-		}
 
-		// Find all the search result blocks with class "gs_ri"
-		s := doc.Find(".gs_ri").First()
-		// Extract the title and URL
-		titleElem := s.Find("h3.gs_rt")
-		title := titleElem.Text()
-		urlResp, _ := titleElem.Find("a").Attr("href")
+	if isGsBlockedResponse(resp) {
+		resp.Body.Close()
+		startScholarCoolDown()
+		return nil, ErrScholarBlocked
+	}
+	if resp.StatusCode != 200 {
+		resp.Body.Close()
+		return nil, fmt.Errorf("failed to retrieve the page, status code %d", resp.StatusCode)
+	}
 
-		// Extract the authors and publication details
-		authorsElem := s.Find("div.gs_a")
-		authors := authorsElem.Text()
+	return resp, nil
+}
 
-		// Extract the abstract or description
-		abstractElem := s.Find("div.gs_rs")
-		abstract := abstractElem.Text()
+// gsGetDocument fetches and parses a Google Scholar page.
+func gsGetDocument(ctx context.Context, urlQuery string) (*goquery.Document, error) {
+	resp, err := gsGet(ctx, urlQuery)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
 
-		return &StudyStruct{Title: title, Url: urlResp, Authors: authors, Abstract: abstract}, true
-	} else {
-		fmt.Println("Failed to retrieve the page. Status code:", resp.StatusCode)
-		return nil, false
+	// Parse the HTML content of the page using goquery
+	doc, err := goquery.NewDocumentFromReader(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error in parsing html: %w", err)
 	}
+
+	// Scholar serves CAPTCHAs and "unusual traffic" pages with a 200
+	if isGsBlockedDocument(doc) {
+		startScholarCoolDown()
+		return nil, ErrScholarBlocked
+	}
+
+	return doc, nil
 }
 
-func QueryTopTenGs(query string, minYear string) (*[]StudyStruct, bool) {
+func fetchGsDocument(ctx context.Context, query string, minYear string) (*goquery.Document, error) {
 	// Define the URL of the Google Scholar search page
 	urlQuery := fmt.Sprintf(
 		"https://scholar.google.com/scholar?hl=en&q=%s&as_ylo=%s",
 		url.QueryEscape(query), minYear,
 	)
-	fmt.Println(urlQuery)
+	return gsGetDocument(ctx, urlQuery)
+}
 
-	// Define a User-Agent header
-	headers := map[string]string{
-		"User-Agent": "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/98.0.4758.102 Safari/537.36",
+// gsResults returns the result blocks of a Scholar page, telling an empty
+// search apart from a page whose layout we can't read.
+func gsResults(doc *goquery.Document) (*goquery.Selection, error) {
+	results := doc.Find(".gs_r").Has(".gs_ri")
+	if results.Length() != 0 {
+		return results, nil
+	}
+	if isGsEmptySearch(doc) {
+		return nil, ErrNoResults
 	}
+	startScholarCoolDown()
+	return nil, ErrScholarBlocked
+}
 
-	// Send a GET request to the URL with the User-Agent header
-	client := &http.Client{}
-	req, err := http.NewRequest("GET", urlQuery, nil)
+// QueryRecentGs returns the Scholar results for query added in the last
+// year, sorted by date as Scholar's own alerts are.
+func QueryRecentGs(ctx context.Context, query string) (*[]StudyStruct, error) {
+	urlQuery := fmt.Sprintf(
+		"https://scholar.google.com/scholar?hl=en&q=%s&scisbd=1",
+		url.QueryEscape(query),
+	)
+	doc, err := gsGetDocument(ctx, urlQuery)
 	if err != nil {
-		log.Printf("error getting the request %v", err)
-		return nil, false
+		return nil, err
 	}
-	for key, value := range headers {
-		req.Header.Add(key, value)
+
+	results, err := gsResults(doc)
+	if err != nil {
+		return nil, err
 	}
 
-	// Check if the request was successful (status code 200)
-	resp, err := client.Do(req)
+	var studySlice []StudyStruct
+	results.Each(func(i int, s *goquery.Selection) {
+		studySlice = append(studySlice, parseGsResult(s))
+	})
+
+	return &studySlice, nil
+}
+
+func QueryFirstGs(ctx context.Context, query string, minYear string) (*StudyStruct, error) {
+	doc, err := fetchGsDocument(ctx, query, minYear)
 	if err != nil {
-		log.Printf("error in executing the request %v", err)
-		return nil, false
+		return nil, err
 	}
-	defer resp.Body.Close()
-	log.Println(resp.StatusCode)
-
-	if resp.StatusCode == 200 {
-		// Parse the HTML content of the page using goquery
-		doc, err := goquery.NewDocumentFromReader(resp.Body)
-		if err != nil {
-			log.Printf("error in parsing html %v", err)
-			return nil, false
-		}
 
-		var studySlice []StudyStruct
+	// Find the first search result block with class "gs_r"
+	results, err := gsResults(doc)
+	if err != nil {
+		return nil, err
+	}
+	study := parseGsResult(results.First())
 
-		// Find all the search result blocks with class "gs_ri"
-		doc.Find(".gs_ri").Each(func(i int, s *goquery.Selection) {
-			// Extract the title and URL
-			titleElem := s.Find("h3.gs_rt")
-			title := titleElem.Text()
-			urlResp, _ := titleElem.Find("a").Attr("href")
+	return &study, nil
+}
 
-			studySlice = append(
-				studySlice,
-				StudyStruct{Title: title, Url: urlResp, Authors: "", Abstract: ""},
-			)
+func QueryTopTenGs(ctx context.Context, query string, minYear string) (*[]StudyStruct, error) {
+	doc, err := fetchGsDocument(ctx, query, minYear)
+	if err != nil {
+		return nil, err
+	}
 
-		})
-		if len(studySlice) != 0 {
-			return &studySlice, true
-		} else {
-			return nil, false
-		}
-	} else {
-		fmt.Println("Failed to retrieve the page. Status code:", resp.StatusCode)
-		return nil, false
+	results, err := gsResults(doc)
+	if err != nil {
+		return nil, err
 	}
+
+	var studySlice []StudyStruct
+
+	// Find all the search result blocks with class "gs_r"
+	results.Each(func(i int, s *goquery.Selection) {
+		studySlice = append(studySlice, parseGsResult(s))
+	})
+
+	return &studySlice, nil
 }
 
-func QueryFirstPMC(query string, minYear string) (*StudyStruct, bool) {
+// searchPubmed runs an ESearch and returns the matching PMIDs by relevance.
+func searchPubmed(ctx context.Context, query string, minYear string, retmax int) ([]string, error) {
+	return esearchPubmed(ctx, url.Values{
+		"term":    {query},
+		"sort":    {"relevance"},
+		"retmax":  {fmt.Sprint(retmax)},
+		"mindate": {minYear},
+		"maxdate": {fmt.Sprint(time.Now().Year())},
+	})
+}
+
+func esearchPubmed(ctx context.Context, params url.Values) ([]string, error) {
 	//https://www.ncbi.nlm.nih.gov/books/NBK25499/#_chapter4_ESearch_
-	urlQuery := fmt.Sprintf(
-		"https://eutils.ncbi.nlm.nih.gov/entrez/eutils/esearch.fcgi?db=pubmed&term=%s&retmode=json&sort=relevance&retmax=1&mindate=%s&maxdate=2024",
-		url.QueryEscape(query),
-		minYear,
-	)
-	fmt.Println(urlQuery)
+	params.Set("db", "pubmed")
+	params.Set("retmode", "json")
+	urlQuery := "https://eutils.ncbi.nlm.nih.gov/entrez/eutils/esearch.fcgi?" + params.Encode()
 
 	// Define a User-Agent header
 	headers := map[string]string{
@@ -165,182 +277,172 @@ func QueryFirstPMC(query string, minYear string) (*StudyStruct, bool) {
 	}
 
 	// Send a GET request to the URL with the User-Agent header
-	client := &http.Client{}
-	req, err := http.NewRequest("GET", urlQuery, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", urlQuery, nil)
 	if err != nil {
-		log.Printf("error getting the request %v", err)
-		return nil, false
+		return nil, fmt.Errorf("error getting the request: %w", err)
 	}
 	for key, value := range headers {
 		req.Header.Add(key, value)
 	}
 
 	// Check if the request was successful (status code 200)
-	resp, err := client.Do(req)
+	resp, err := sendRequest(ctx, "pubmed", req)
 	if err != nil {
-		log.Printf("error in executing the request %v", err)
-		return nil, false
+		return nil, err
 	}
 	defer resp.Body.Close()
-	log.Println(resp.StatusCode)
-
-	if resp.StatusCode == 200 {
-		var idStudyList IdStudyList
-		err := json.NewDecoder(resp.Body).Decode(&idStudyList)
-		if err != nil {
-			log.Printf("error translating json response from PMC API %v", err)
-			return nil, false
-		}
-		if len(idStudyList.Esearchresult.Idlist) != 0 {
-			idStudy := idStudyList.Esearchresult.Idlist[0]
-			urlStudy := fmt.Sprintf(
-				"https://eutils.ncbi.nlm.nih.gov/entrez/eutils/efetch.fcgi?db=pubmed&id=%s",
-				idStudy,
-			)
-			log.Println(urlStudy)
-			req, err := http.NewRequest("GET", urlStudy, nil)
-			if err != nil {
-				log.Printf("failed to form new request to get study details %v", err)
-				return nil, false
-			}
-			headers := map[string]string{
-				"User-Agent":   "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/98.0.4758.102 Safari/537.36",
-				"Content-Type": "application/xml",
-			}
-			for key, value := range headers {
-				req.Header.Add(key, value)
-			}
 
-			// Check if the request was successful (status code 200)
-			studyResp, err := client.Do(req)
-			if err != nil {
-				log.Printf("error in executing the request %v", err)
-				return nil, false
-			}
-			defer studyResp.Body.Close()
-			log.Println(studyResp.StatusCode)
-			if studyResp.StatusCode == 200 {
-				var pubmedArticleSet PubmedArticleSet
-				err := xml.NewDecoder(studyResp.Body).Decode(&pubmedArticleSet)
-				if err != nil {
-					log.Printf("Error decoding url  data for pmc study %v", err)
-					return nil, false
-				}
-				if len(pubmedArticleSet.PubmedArticle) != 0 {
-					title := pubmedArticleSet.PubmedArticle[0].MedlineCitation.Article.ArticleTitle
-					urlArticle := fmt.Sprintf(
-						"https://pubmed.ncbi.nlm.nih.gov/%s/",
-						idStudyList.Esearchresult.Idlist[0],
-					)
-					//handle authors
-					abstract := pubmedArticleSet.PubmedArticle[0].MedlineCitation.Article.Abstract.AbstractText
-					return &StudyStruct{
-						Title:    title,
-						Url:      urlArticle,
-						Authors:  "",
-						Abstract: abstract,
-					}, true
-				}
-			}
-		}
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("failed to search PubMed, status code %d", resp.StatusCode)
+	}
+
+	var idStudyList IdStudyList
+	err = json.NewDecoder(resp.Body).Decode(&idStudyList)
+	if err != nil {
+		return nil, fmt.Errorf("error translating json response from PMC API: %w", err)
+	}
+	if len(idStudyList.Esearchresult.Idlist) == 0 {
+		return nil, ErrNoResults
 	}
 
-	return nil, false
+	return idStudyList.Esearchresult.Idlist, nil
 }
 
-func QueryTopTenPMC(query string, minYear string) (*[]StudyStruct, bool) {
-	//https://www.ncbi.nlm.nih.gov/books/NBK25499/#_chapter4_ESearch_
-	urlQuery := fmt.Sprintf(
-		"https://eutils.ncbi.nlm.nih.gov/entrez/eutils/esearch.fcgi?db=pubmed&term=%s&retmode=json&sort=relevance&retmax=10&mindate=%s&maxdate=2024",
-		url.QueryEscape(query),
-		minYear,
+// QueryPMCByIds fetches the PubMed records for the given PMIDs, in order.
+func QueryPMCByIds(ctx context.Context, ids []string) (*[]StudyStruct, error) {
+	//https://www.ncbi.nlm.nih.gov/books/NBK25499/#_chapter4_EFetch_
+	urlStudy := fmt.Sprintf(
+		"https://eutils.ncbi.nlm.nih.gov/entrez/eutils/efetch.fcgi?db=pubmed&id=%s",
+		url.QueryEscape(strings.Join(ids, ",")),
 	)
-	fmt.Println(urlQuery)
 
-	// Define a User-Agent header
+	req, err := http.NewRequestWithContext(ctx, "GET", urlStudy, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to form new request to get study details: %w", err)
+	}
 	headers := map[string]string{
 		"User-Agent":   "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/98.0.4758.102 Safari/537.36",
-		"Content-Type": "application/json",
-	}
-
-	// Send a GET request to the URL with the User-Agent header
-	client := &http.Client{}
-	req, err := http.NewRequest("GET", urlQuery, nil)
-	if err != nil {
-		log.Printf("error getting the request %v", err)
-		return nil, false
+		"Content-Type": "application/xml",
 	}
 	for key, value := range headers {
 		req.Header.Add(key, value)
 	}
 
 	// Check if the request was successful (status code 200)
-	resp, err := client.Do(req)
+	studyResp, err := sendRequest(ctx, "pubmed", req)
 	if err != nil {
-		log.Printf("error in executing the request %v", err)
-		return nil, false
+		return nil, err
+	}
+	defer studyResp.Body.Close()
+	if studyResp.StatusCode != 200 {
+		return nil, fmt.Errorf("failed to fetch PubMed studies, status code %d", studyResp.StatusCode)
 	}
-	defer resp.Body.Close()
-	log.Println(resp.StatusCode)
-
-	if resp.StatusCode == 200 {
-		var idStudyList IdStudyList
-		err := json.NewDecoder(resp.Body).Decode(&idStudyList)
-		if err != nil {
-			log.Printf("error translating json response from PMC API %v", err)
-			return nil, false
-		}
-		if len(idStudyList.Esearchresult.Idlist) != 0 {
-			idStudies := strings.Join(idStudyList.Esearchresult.Idlist, ",")
-			urlStudy := fmt.Sprintf(
-				"https://eutils.ncbi.nlm.nih.gov/entrez/eutils/efetch.fcgi?db=pubmed&id=%s",
-				idStudies,
-			)
-			log.Println(urlStudy)
-			req, err := http.NewRequest("GET", urlStudy, nil)
-			if err != nil {
-				log.Printf("failed to form new request to get study details %v", err)
-				return nil, false
-			}
-			headers := map[string]string{
-				"User-Agent":   "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/98.0.4758.102 Safari/537.36",
-				"Content-Type": "application/xml",
-			}
-			for key, value := range headers {
-				req.Header.Add(key, value)
-			}
 
-			// Check if the request was successful (status code 200)
-			studyResp, err := client.Do(req)
-			if err != nil {
-				log.Printf("error in executing the request %v", err)
-				return nil, false
-			}
-			defer studyResp.Body.Close()
-			log.Println(studyResp.StatusCode)
-			if studyResp.StatusCode == 200 {
-				var pubmedArticleSet PubmedArticleSet
-				err := xml.NewDecoder(studyResp.Body).Decode(&pubmedArticleSet)
-				if err != nil {
-					log.Printf("Error decoding url  data for pmc study %v", err)
-					return nil, false
-				}
-				var studyStructSlice []StudyStruct
-				for _, value := range pubmedArticleSet.PubmedArticle {
-					title := value.MedlineCitation.Article.ArticleTitle
-					urlArticle := fmt.Sprintf(
-						"https://pubmed.ncbi.nlm.nih.gov/%s/",
-						value.MedlineCitation.PMID.Text,
-					)
-					studyStructSlice = append(studyStructSlice, StudyStruct{Title: title, Url: urlArticle, Authors: "", Abstract: ""})
-
-				}
-				if len(studyStructSlice) != 0 {
-					return &studyStructSlice, true
-				}
+	var pubmedArticleSet PubmedArticleSet
+	err = xml.NewDecoder(studyResp.Body).Decode(&pubmedArticleSet)
+	if err != nil {
+		return nil, fmt.Errorf("error decoding url data for pmc study: %w", err)
+	}
+	var studyStructSlice []StudyStruct
+	for _, value := range pubmedArticleSet.PubmedArticle {
+		studyStructSlice = append(studyStructSlice, studyFromPubmedArticle(value))
+	}
+	if len(studyStructSlice) == 0 {
+		return nil, ErrNoResults
+	}
+
+	return &studyStructSlice, nil
+}
+
+func studyFromPubmedArticle(value PubmedArticle) StudyStruct {
+	article := value.MedlineCitation.Article
+	journalIssue := article.Journal.JournalIssue
+	study := StudyStruct{
+		Title:         article.ArticleTitle,
+		Url:           fmt.Sprintf("https://pubmed.ncbi.nlm.nih.gov/%s/", value.MedlineCitation.PMID.Text),
+		Abstract:      article.Abstract.AbstractText,
+		Source:        "pubmed",
+		Venue:         article.Journal.Title,
+		JournalAbbrev: article.Journal.ISOAbbreviation,
+		Year:          journalIssue.PubDate.Year,
+		Month:         journalIssue.PubDate.Month,
+		Volume:        journalIssue.Volume,
+		Issue:         journalIssue.Issue,
+		Pages:         article.Pagination.MedlinePgn,
+		Pmid:          value.MedlineCitation.PMID.Text,
+	}
+	// Older records only carry a free text date such as "1998 Dec-1999 Jan"
+	if study.Year == "" && len(journalIssue.PubDate.MedlineDate) >= 4 {
+		study.Year = journalIssue.PubDate.MedlineDate[:4]
+	}
+
+	var authorNames []string
+	for _, author := range article.AuthorList.Author {
+		study.AuthorList = append(study.AuthorList, Author{
+			LastName: author.LastName,
+			ForeName: author.ForeName,
+			Initials: author.Initials,
+		})
+		authorNames = append(authorNames, strings.TrimSpace(author.LastName+" "+author.Initials))
+	}
+	study.Authors = strings.Join(authorNames, ", ")
+
+	for _, articleId := range value.PubmedData.ArticleIdList.ArticleId {
+		switch articleId.IdType {
+		case "doi":
+			study.Doi = articleId.Text
+		case "pmc":
+			study.Pmcid = articleId.Text
+		}
+	}
+	if study.Doi == "" {
+		for _, location := range article.ELocationID {
+			if location.EIdType == "doi" {
+				study.Doi = location.Text
 			}
 		}
 	}
 
-	return nil, false
+	return study
+}
+
+// QueryRecentPMC returns the studies matching query that entered PubMed
+// (Entrez date) on or after since, newest first.
+func QueryRecentPMC(ctx context.Context, query string, since time.Time) (*[]StudyStruct, error) {
+	ids, err := esearchPubmed(ctx, url.Values{
+		"term":     {query},
+		"sort":     {"pub_date"},
+		"retmax":   {"20"},
+		"datetype": {"edat"},
+		"mindate":  {since.UTC().Format("2006/01/02")},
+		"maxdate":  {time.Now().UTC().AddDate(0, 0, 1).Format("2006/01/02")},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return QueryPMCByIds(ctx, ids)
+}
+
+func QueryFirstPMC(ctx context.Context, query string, minYear string) (*StudyStruct, error) {
+	ids, err := searchPubmed(ctx, query, minYear, 1)
+	if err != nil {
+		return nil, err
+	}
+
+	studySlice, err := QueryPMCByIds(ctx, ids[:1])
+	if err != nil {
+		return nil, err
+	}
+
+	return &(*studySlice)[0], nil
+}
+
+func QueryTopTenPMC(ctx context.Context, query string, minYear string) (*[]StudyStruct, error) {
+	ids, err := searchPubmed(ctx, query, minYear, 10)
+	if err != nil {
+		return nil, err
+	}
+
+	return QueryPMCByIds(ctx, ids)
 }
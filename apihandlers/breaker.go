@@ -0,0 +1,159 @@
+package apihandlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"scholar-bot/metrics"
+)
+
+// ErrCircuitOpen is returned without contacting a source that failed
+// repeatedly, until its breaker lets a trial request through again.
+var ErrCircuitOpen = errors.New("source is not responding")
+
+// BreakerState is the state of a circuit breaker.
+type BreakerState int
+
+const (
+	// BreakerClosed lets every request through.
+	BreakerClosed BreakerState = iota
+	// BreakerHalfOpen lets a single trial request through.
+	BreakerHalfOpen
+	// BreakerOpen refuses every request.
+	BreakerOpen
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerHalfOpen:
+		return "half-open"
+	case BreakerOpen:
+		return "open"
+	}
+	return "closed"
+}
+
+var (
+	breakerState = metrics.NewGauge(
+		"scholar_bot_circuit_state",
+		"State of each source's circuit breaker: 0 closed, 1 half-open, 2 open.",
+		"source",
+	)
+	breakerOpens = metrics.NewCounter(
+		"scholar_bot_circuit_opens_total",
+		"Times each source's circuit breaker opened.",
+		"source",
+	)
+)
+
+// Breaker stops sending requests to a source after Threshold consecutive
+// failures, then tries a single request every OpenFor until one succeeds.
+type Breaker struct {
+	source string
+	now    func() time.Time
+
+	mu        sync.Mutex
+	threshold int
+	openFor   time.Duration
+	state     BreakerState
+	failures  int
+	openedAt  time.Time
+	lastError error
+}
+
+// NewBreaker returns a closed breaker for the named source.
+func NewBreaker(source string, threshold int, openFor time.Duration) *Breaker {
+	b := &Breaker{source: source, now: time.Now, threshold: threshold, openFor: openFor}
+	breakerState.Set(float64(BreakerClosed), source)
+	return b
+}
+
+// Breakers guard the requests to each source.
+var Breakers = map[string]*Breaker{
+	"scholar": NewBreaker("scholar", 5, time.Minute),
+	"pubmed":  NewBreaker("pubmed", 5, time.Minute),
+}
+
+// SetPolicy changes after how many consecutive failures the breaker opens
+// and how long it stays open.
+func (b *Breaker) SetPolicy(threshold int, openFor time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.threshold = max(threshold, 1)
+	b.openFor = openFor
+}
+
+// BreakerStatus describes a breaker for /status.
+type BreakerStatus struct {
+	State     BreakerState
+	Failures  int
+	RetryIn   time.Duration
+	LastError error
+}
+
+// Status returns the current state of the breaker.
+func (b *Breaker) Status() BreakerStatus {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	status := BreakerStatus{State: b.state, Failures: b.failures, LastError: b.lastError}
+	if b.state == BreakerOpen {
+		status.RetryIn = max(b.openedAt.Add(b.openFor).Sub(b.now()), 0)
+	}
+	return status
+}
+
+// allow reports whether a request may be sent now. When it may, the
+// returned function must be called with the outcome of the request.
+func (b *Breaker) allow() (func(err error), error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.state {
+	case BreakerOpen:
+		retryIn := b.openedAt.Add(b.openFor).Sub(b.now())
+		if retryIn > 0 {
+			return nil, fmt.Errorf("%w (retrying in %s)", ErrCircuitOpen, retryIn.Round(time.Second))
+		}
+		b.setState(BreakerHalfOpen)
+	case BreakerHalfOpen:
+		// A trial request is already in flight
+		return nil, ErrCircuitOpen
+	}
+	return b.record, nil
+}
+
+// record updates the breaker with the outcome of a request. Requests the
+// caller cancelled say nothing about the source.
+func (b *Breaker) record(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch {
+	case errors.Is(err, context.Canceled):
+		if b.state == BreakerHalfOpen {
+			// Let the next request be the trial
+			b.setState(BreakerOpen)
+			b.openedAt = b.now().Add(-b.openFor)
+		}
+	case err != nil:
+		b.failures++
+		b.lastError = err
+		if b.state == BreakerHalfOpen || b.failures >= b.threshold {
+			if b.state != BreakerOpen {
+				breakerOpens.Inc(b.source)
+			}
+			b.setState(BreakerOpen)
+			b.openedAt = b.now()
+		}
+	default:
+		b.failures = 0
+		b.setState(BreakerClosed)
+	}
+}
+
+// setState changes the state and its gauge. The caller holds mu.
+func (b *Breaker) setState(state BreakerState) {
+	b.state = state
+	breakerState.Set(float64(state), b.source)
+}
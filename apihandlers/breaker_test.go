@@ -0,0 +1,192 @@
+package apihandlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// testClock is a clock moved by hand.
+type testClock struct {
+	now time.Time
+}
+
+func (c *testClock) Now() time.Time { return c.now }
+
+func newTestBreaker(threshold int, openFor time.Duration) (*Breaker, *testClock) {
+	clock := &testClock{now: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)}
+	breaker := NewBreaker("test", threshold, openFor)
+	breaker.now = clock.Now
+	return breaker, clock
+}
+
+func TestBreaker(t *testing.T) {
+	failure := errors.New("failed")
+	// step is a request sent after waiting: either allowed and ending with
+	// err, or refused
+	type step struct {
+		wait    time.Duration
+		refused bool
+		err     error
+		want    BreakerState
+	}
+	tests := []struct {
+		name  string
+		steps []step
+	}{
+		{
+			name: "opens after the threshold",
+			steps: []step{
+				{err: failure, want: BreakerClosed},
+				{err: failure, want: BreakerClosed},
+				{err: failure, want: BreakerOpen},
+				{refused: true, want: BreakerOpen},
+				{wait: 59 * time.Second, refused: true, want: BreakerOpen},
+			},
+		},
+		{
+			name: "success resets the failures",
+			steps: []step{
+				{err: failure, want: BreakerClosed},
+				{err: failure, want: BreakerClosed},
+				{want: BreakerClosed},
+				{err: failure, want: BreakerClosed},
+				{err: failure, want: BreakerClosed},
+				{err: failure, want: BreakerOpen},
+			},
+		},
+		{
+			name: "trial success closes",
+			steps: []step{
+				{err: failure}, {err: failure}, {err: failure, want: BreakerOpen},
+				{wait: time.Minute, want: BreakerClosed},
+				{err: failure, want: BreakerClosed},
+			},
+		},
+		{
+			name: "trial failure reopens",
+			steps: []step{
+				{err: failure}, {err: failure}, {err: failure, want: BreakerOpen},
+				{wait: time.Minute, err: failure, want: BreakerOpen},
+				{wait: 59 * time.Second, refused: true, want: BreakerOpen},
+				{wait: time.Second, want: BreakerClosed},
+			},
+		},
+		{
+			name: "cancelled trial lets the next request try",
+			steps: []step{
+				{err: failure}, {err: failure}, {err: failure, want: BreakerOpen},
+				{wait: time.Minute, err: context.Canceled, want: BreakerOpen},
+				{want: BreakerClosed},
+			},
+		},
+		{
+			name: "cancelled requests don't count",
+			steps: []step{
+				{err: failure}, {err: failure},
+				{err: context.Canceled, want: BreakerClosed},
+				{err: context.Canceled, want: BreakerClosed},
+				{err: failure, want: BreakerOpen},
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			breaker, clock := newTestBreaker(3, time.Minute)
+			for i, step := range test.steps {
+				clock.now = clock.now.Add(step.wait)
+				done, err := breaker.allow()
+				if step.refused {
+					if !errors.Is(err, ErrCircuitOpen) {
+						t.Fatalf("step %d: allow = %v, want ErrCircuitOpen", i, err)
+					}
+				} else {
+					if err != nil {
+						t.Fatalf("step %d: allow = %v", i, err)
+					}
+					done(step.err)
+				}
+				if state := breaker.Status().State; state != step.want {
+					t.Fatalf("step %d: state = %s, want %s", i, state, step.want)
+				}
+			}
+		})
+	}
+}
+
+func TestBreakerHalfOpenAllowsOneTrial(t *testing.T) {
+	breaker, clock := newTestBreaker(1, time.Minute)
+	done, _ := breaker.allow()
+	done(errors.New("failed"))
+	if retryIn := breaker.Status().RetryIn; retryIn != time.Minute {
+		t.Errorf("RetryIn = %s, want 1m", retryIn)
+	}
+
+	clock.now = clock.now.Add(time.Minute)
+	trial, err := breaker.allow()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state := breaker.Status().State; state != BreakerHalfOpen {
+		t.Errorf("state = %s during the trial, want half-open", state)
+	}
+	if _, err := breaker.allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("second request during the trial = %v, want ErrCircuitOpen", err)
+	}
+	trial(nil)
+	if _, err := breaker.allow(); err != nil {
+		t.Errorf("request after the trial = %v", err)
+	}
+}
+
+func TestSendRequestServerErrorsTrip(t *testing.T) {
+	status := http.StatusInternalServerError
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	breaker, _ := newTestBreaker(2, time.Minute)
+	Breakers["test"] = breaker
+	Schedulers["test"] = NewScheduler("test", 1)
+	defer func() {
+		delete(Breakers, "test")
+		delete(Schedulers, "test")
+	}()
+
+	send := func() error {
+		req, err := http.NewRequest(http.MethodGet, server.URL, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := sendRequest(context.Background(), "test", req)
+		if err != nil {
+			return err
+		}
+		return resp.Body.Close()
+	}
+
+	// Client errors say nothing about the source's health
+	status = http.StatusNotFound
+	for range 3 {
+		if err := send(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if state := breaker.Status().State; state != BreakerClosed {
+		t.Fatalf("state = %s after 404s, want closed", state)
+	}
+
+	status = http.StatusBadGateway
+	for range 2 {
+		if err := send(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := send(); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("request after two 502s = %v, want ErrCircuitOpen", err)
+	}
+}
@@ -52,137 +52,144 @@ type StudyLinks struct {
 }
 
 type PubmedArticleSet struct {
-	XMLName       xml.Name `xml:"PubmedArticleSet"`
-	Text          string   `xml:",chardata"`
-	PubmedArticle []struct {
-		Text            string `xml:",chardata"`
-		MedlineCitation struct {
-			Text   string `xml:",chardata"`
-			Status string `xml:"Status,attr"`
-			Owner  string `xml:"Owner,attr"`
-			PMID   struct {
-				Text    string `xml:",chardata"`
-				Version string `xml:"Version,attr"`
-			} `xml:"PMID"`
-			DateCompleted struct {
-				Text  string `xml:",chardata"`
-				Year  string `xml:"Year"`
-				Month string `xml:"Month"`
-				Day   string `xml:"Day"`
-			} `xml:"DateCompleted"`
-			DateRevised struct {
-				Text  string `xml:",chardata"`
-				Year  string `xml:"Year"`
-				Month string `xml:"Month"`
-				Day   string `xml:"Day"`
-			} `xml:"DateRevised"`
-			Article struct {
-				Text     string `xml:",chardata"`
-				PubModel string `xml:"PubModel,attr"`
-				Journal  struct {
-					Text string `xml:",chardata"`
-					ISSN struct {
-						Text     string `xml:",chardata"`
-						IssnType string `xml:"IssnType,attr"`
-					} `xml:"ISSN"`
-					JournalIssue struct {
+	XMLName       xml.Name        `xml:"PubmedArticleSet"`
+	Text          string          `xml:",chardata"`
+	PubmedArticle []PubmedArticle `xml:"PubmedArticle"`
+}
+
+type PubmedArticle struct {
+	Text            string `xml:",chardata"`
+	MedlineCitation struct {
+		Text   string `xml:",chardata"`
+		Status string `xml:"Status,attr"`
+		Owner  string `xml:"Owner,attr"`
+		PMID   struct {
+			Text    string `xml:",chardata"`
+			Version string `xml:"Version,attr"`
+		} `xml:"PMID"`
+		DateCompleted struct {
+			Text  string `xml:",chardata"`
+			Year  string `xml:"Year"`
+			Month string `xml:"Month"`
+			Day   string `xml:"Day"`
+		} `xml:"DateCompleted"`
+		DateRevised struct {
+			Text  string `xml:",chardata"`
+			Year  string `xml:"Year"`
+			Month string `xml:"Month"`
+			Day   string `xml:"Day"`
+		} `xml:"DateRevised"`
+		Article struct {
+			Text     string `xml:",chardata"`
+			PubModel string `xml:"PubModel,attr"`
+			Journal  struct {
+				Text string `xml:",chardata"`
+				ISSN struct {
+					Text     string `xml:",chardata"`
+					IssnType string `xml:"IssnType,attr"`
+				} `xml:"ISSN"`
+				JournalIssue struct {
+					Text        string `xml:",chardata"`
+					CitedMedium string `xml:"CitedMedium,attr"`
+					Volume      string `xml:"Volume"`
+					Issue       string `xml:"Issue"`
+					PubDate     struct {
 						Text        string `xml:",chardata"`
-						CitedMedium string `xml:"CitedMedium,attr"`
-						Volume      string `xml:"Volume"`
-						Issue       string `xml:"Issue"`
-						PubDate     struct {
-							Text  string `xml:",chardata"`
-							Year  string `xml:"Year"`
-							Month string `xml:"Month"`
-						} `xml:"PubDate"`
-					} `xml:"JournalIssue"`
-					Title           string `xml:"Title"`
-					ISOAbbreviation string `xml:"ISOAbbreviation"`
-				} `xml:"Journal"`
-				ArticleTitle string `xml:"ArticleTitle"`
-				Pagination   struct {
-					Text       string `xml:",chardata"`
-					StartPage  string `xml:"StartPage"`
-					EndPage    string `xml:"EndPage"`
-					MedlinePgn string `xml:"MedlinePgn"`
-				} `xml:"Pagination"`
-				Abstract struct {
-					Text                 string `xml:",chardata"`
-					AbstractText         string `xml:"AbstractText"`
-					CopyrightInformation string `xml:"CopyrightInformation"`
-				} `xml:"Abstract"`
-				AuthorList struct {
-					Text       string `xml:",chardata"`
-					CompleteYN string `xml:"CompleteYN,attr"`
-					Author     []struct {
-						Text            string `xml:",chardata"`
-						ValidYN         string `xml:"ValidYN,attr"`
-						LastName        string `xml:"LastName"`
-						ForeName        string `xml:"ForeName"`
-						Initials        string `xml:"Initials"`
-						AffiliationInfo struct {
-							Text        string `xml:",chardata"`
-							Affiliation string `xml:"Affiliation"`
-						} `xml:"AffiliationInfo"`
-					} `xml:"Author"`
-				} `xml:"AuthorList"`
-				Language            string `xml:"Language"`
-				PublicationTypeList struct {
+						Year        string `xml:"Year"`
+						Month       string `xml:"Month"`
+						Day         string `xml:"Day"`
+						MedlineDate string `xml:"MedlineDate"`
+					} `xml:"PubDate"`
+				} `xml:"JournalIssue"`
+				Title           string `xml:"Title"`
+				ISOAbbreviation string `xml:"ISOAbbreviation"`
+			} `xml:"Journal"`
+			ArticleTitle string `xml:"ArticleTitle"`
+			ELocationID  []struct {
+				Text    string `xml:",chardata"`
+				EIdType string `xml:"EIdType,attr"`
+			} `xml:"ELocationID"`
+			Pagination struct {
+				Text       string `xml:",chardata"`
+				StartPage  string `xml:"StartPage"`
+				EndPage    string `xml:"EndPage"`
+				MedlinePgn string `xml:"MedlinePgn"`
+			} `xml:"Pagination"`
+			Abstract struct {
+				Text                 string `xml:",chardata"`
+				AbstractText         string `xml:"AbstractText"`
+				CopyrightInformation string `xml:"CopyrightInformation"`
+			} `xml:"Abstract"`
+			AuthorList struct {
+				Text       string `xml:",chardata"`
+				CompleteYN string `xml:"CompleteYN,attr"`
+				Author     []struct {
 					Text            string `xml:",chardata"`
-					PublicationType []struct {
-						Text string `xml:",chardata"`
-						UI   string `xml:"UI,attr"`
-					} `xml:"PublicationType"`
-				} `xml:"PublicationTypeList"`
-			} `xml:"Article"`
-			MedlineJournalInfo struct {
-				Text        string `xml:",chardata"`
-				Country     string `xml:"Country"`
-				MedlineTA   string `xml:"MedlineTA"`
-				NlmUniqueID string `xml:"NlmUniqueID"`
-				ISSNLinking string `xml:"ISSNLinking"`
-			} `xml:"MedlineJournalInfo"`
-			CitationSubset  string `xml:"CitationSubset"`
-			MeshHeadingList struct {
-				Text        string `xml:",chardata"`
-				MeshHeading []struct {
-					Text           string `xml:",chardata"`
-					DescriptorName struct {
-						Text         string `xml:",chardata"`
-						UI           string `xml:"UI,attr"`
-						MajorTopicYN string `xml:"MajorTopicYN,attr"`
-					} `xml:"DescriptorName"`
-					QualifierName []struct {
-						Text         string `xml:",chardata"`
-						UI           string `xml:"UI,attr"`
-						MajorTopicYN string `xml:"MajorTopicYN,attr"`
-					} `xml:"QualifierName"`
-				} `xml:"MeshHeading"`
-			} `xml:"MeshHeadingList"`
-		} `xml:"MedlineCitation"`
-		PubmedData struct {
-			Text    string `xml:",chardata"`
-			History struct {
-				Text          string `xml:",chardata"`
-				PubMedPubDate []struct {
-					Text      string `xml:",chardata"`
-					PubStatus string `xml:"PubStatus,attr"`
-					Year      string `xml:"Year"`
-					Month     string `xml:"Month"`
-					Day       string `xml:"Day"`
-					Hour      string `xml:"Hour"`
-					Minute    string `xml:"Minute"`
-				} `xml:"PubMedPubDate"`
-			} `xml:"History"`
-			PublicationStatus string `xml:"PublicationStatus"`
-			ArticleIdList     struct {
+					ValidYN         string `xml:"ValidYN,attr"`
+					LastName        string `xml:"LastName"`
+					ForeName        string `xml:"ForeName"`
+					Initials        string `xml:"Initials"`
+					AffiliationInfo struct {
+						Text        string `xml:",chardata"`
+						Affiliation string `xml:"Affiliation"`
+					} `xml:"AffiliationInfo"`
+				} `xml:"Author"`
+			} `xml:"AuthorList"`
+			Language            string `xml:"Language"`
+			PublicationTypeList struct {
+				Text            string `xml:",chardata"`
+				PublicationType []struct {
+					Text string `xml:",chardata"`
+					UI   string `xml:"UI,attr"`
+				} `xml:"PublicationType"`
+			} `xml:"PublicationTypeList"`
+		} `xml:"Article"`
+		MedlineJournalInfo struct {
+			Text        string `xml:",chardata"`
+			Country     string `xml:"Country"`
+			MedlineTA   string `xml:"MedlineTA"`
+			NlmUniqueID string `xml:"NlmUniqueID"`
+			ISSNLinking string `xml:"ISSNLinking"`
+		} `xml:"MedlineJournalInfo"`
+		CitationSubset  string `xml:"CitationSubset"`
+		MeshHeadingList struct {
+			Text        string `xml:",chardata"`
+			MeshHeading []struct {
+				Text           string `xml:",chardata"`
+				DescriptorName struct {
+					Text         string `xml:",chardata"`
+					UI           string `xml:"UI,attr"`
+					MajorTopicYN string `xml:"MajorTopicYN,attr"`
+				} `xml:"DescriptorName"`
+				QualifierName []struct {
+					Text         string `xml:",chardata"`
+					UI           string `xml:"UI,attr"`
+					MajorTopicYN string `xml:"MajorTopicYN,attr"`
+				} `xml:"QualifierName"`
+			} `xml:"MeshHeading"`
+		} `xml:"MeshHeadingList"`
+	} `xml:"MedlineCitation"`
+	PubmedData struct {
+		Text    string `xml:",chardata"`
+		History struct {
+			Text          string `xml:",chardata"`
+			PubMedPubDate []struct {
 				Text      string `xml:",chardata"`
-				ArticleId []struct {
-					Text   string `xml:",chardata"`
-					IdType string `xml:"IdType,attr"`
-				} `xml:"ArticleId"`
-			} `xml:"ArticleIdList"`
-		} `xml:"PubmedData"`
-	} `xml:"PubmedArticle"`
+				PubStatus string `xml:"PubStatus,attr"`
+				Year      string `xml:"Year"`
+				Month     string `xml:"Month"`
+				Day       string `xml:"Day"`
+				Hour      string `xml:"Hour"`
+				Minute    string `xml:"Minute"`
+			} `xml:"PubMedPubDate"`
+		} `xml:"History"`
+		PublicationStatus string `xml:"PublicationStatus"`
+		ArticleIdList     struct {
+			Text      string `xml:",chardata"`
+			ArticleId []struct {
+				Text   string `xml:",chardata"`
+				IdType string `xml:"IdType,attr"`
+			} `xml:"ArticleId"`
+		} `xml:"ArticleIdList"`
+	} `xml:"PubmedData"`
 }
-
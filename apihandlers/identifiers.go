@@ -0,0 +1,114 @@
+package apihandlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Identifier returns a stable identifier for the study: "pmid:<PMID>",
+// "doi:<DOI>" or "gs:<Scholar data-cid>", in that order of preference.
+// It is empty when the source gave nothing to look the study up by.
+func (study StudyStruct) Identifier() string {
+	switch {
+	case study.Pmid != "":
+		return "pmid:" + study.Pmid
+	case study.Doi != "":
+		return "doi:" + study.Doi
+	case study.CiteId != "":
+		return "gs:" + study.CiteId
+	}
+	return ""
+}
+
+// ErrInvalidPmid is returned by ParseIdentifier for a "pmid:" identifier
+// that isn't a number.
+var ErrInvalidPmid = errors.New("PMIDs are numbers")
+
+// ParseIdentifier normalizes user input into an identifier. Bare numbers are
+// taken as PMIDs and strings starting with "10." as DOIs.
+func ParseIdentifier(input string) (string, error) {
+	input = strings.TrimSpace(input)
+	input = strings.TrimPrefix(input, "https://doi.org/")
+	if strings.HasPrefix(input, "https://pubmed.ncbi.nlm.nih.gov/") {
+		input = "pmid:" + strings.Trim(strings.TrimPrefix(input, "https://pubmed.ncbi.nlm.nih.gov/"), "/")
+	}
+
+	kind, value, found := strings.Cut(input, ":")
+	if found {
+		switch strings.ToLower(kind) {
+		case "pmid":
+			if !isPmid(value) {
+				return "", fmt.Errorf("%q is not a PMID: %w", value, ErrInvalidPmid)
+			}
+			return "pmid:" + value, nil
+		case "doi", "gs":
+			if value != "" {
+				return strings.ToLower(kind) + ":" + value, nil
+			}
+		}
+	}
+	if strings.HasPrefix(input, "10.") {
+		return "doi:" + input, nil
+	}
+	if isPmid(input) {
+		return "pmid:" + input, nil
+	}
+	return "", fmt.Errorf("%q is not a PMID, DOI or Scholar id", input)
+}
+
+func isPmid(value string) bool {
+	return value != "" && strings.Trim(value, "0123456789") == ""
+}
+
+// QueryByIdentifier looks a single study up by an identifier as returned by
+// StudyStruct.Identifier.
+func QueryByIdentifier(ctx context.Context, identifier string) (study *StudyStruct, err error) {
+	kind, value, _ := strings.Cut(identifier, ":")
+	source := "pubmed"
+	if kind == "gs" {
+		source = "scholar"
+	}
+	defer func(start time.Time) {
+		observeQuery(ctx, source, "identifier", start, resultCount(study != nil), err)
+	}(time.Now())
+	switch kind {
+	case "pmid":
+		studySlice, err := QueryPMCByIds(ctx, []string{value})
+		if err != nil {
+			return nil, err
+		}
+		return &(*studySlice)[0], nil
+	case "doi":
+		return QueryFirstPMC(ctx, value+"[doi]", "1800")
+	case "gs":
+		return QueryGsById(ctx, value)
+	}
+	return nil, fmt.Errorf("unknown identifier %q", identifier)
+}
+
+// QueryGsById finds the Scholar result with the given data-cid.
+func QueryGsById(ctx context.Context, citeId string) (*StudyStruct, error) {
+	urlQuery := fmt.Sprintf(
+		"https://scholar.google.com/scholar?hl=en&q=info:%s:scholar.google.com/",
+		url.QueryEscape(citeId),
+	)
+	doc, err := gsGetDocument(ctx, urlQuery)
+	if err != nil {
+		return nil, err
+	}
+
+	results, err := gsResults(doc)
+	if err != nil {
+		return nil, err
+	}
+	study := parseGsResult(results.First())
+	if study.CiteId == "" {
+		study.CiteId = citeId
+	}
+
+	return &study, nil
+}
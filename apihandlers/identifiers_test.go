@@ -0,0 +1,50 @@
+package apihandlers
+
+import "testing"
+
+func TestParseIdentifier(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+		err   bool
+	}{
+		{input: "pmid:33283989", want: "pmid:33283989"},
+		{input: "PMID:33283989", want: "pmid:33283989"},
+		{input: " 33283989 ", want: "pmid:33283989"},
+		{input: "https://pubmed.ncbi.nlm.nih.gov/33283989/", want: "pmid:33283989"},
+		{input: "doi:10.1056/NEJMoa2031054", want: "doi:10.1056/NEJMoa2031054"},
+		{input: "10.1056/NEJMoa2031054", want: "doi:10.1056/NEJMoa2031054"},
+		{input: "https://doi.org/10.1056/NEJMoa2031054", want: "doi:10.1056/NEJMoa2031054"},
+		{input: "gs:AbCdEf123", want: "gs:AbCdEf123"},
+		{input: "", err: true},
+		{input: "pmid:", err: true},
+		{input: "pmid:abc", err: true},
+		{input: "PMID:abc", err: true},
+		{input: "pmid:123abc", err: true},
+		{input: "https://pubmed.ncbi.nlm.nih.gov/abc/", err: true},
+		{input: "crispr", err: true},
+	}
+	for _, test := range tests {
+		got, err := ParseIdentifier(test.input)
+		if (err != nil) != test.err || got != test.want {
+			t.Errorf("ParseIdentifier(%q) = %q, %v, want %q", test.input, got, err, test.want)
+		}
+	}
+}
+
+func TestStudyIdentifier(t *testing.T) {
+	tests := []struct {
+		study StudyStruct
+		want  string
+	}{
+		{study: StudyStruct{Pmid: "1", Doi: "10.1/x", CiteId: "c"}, want: "pmid:1"},
+		{study: StudyStruct{Doi: "10.1/x", CiteId: "c"}, want: "doi:10.1/x"},
+		{study: StudyStruct{CiteId: "c"}, want: "gs:c"},
+		{study: StudyStruct{Title: "untitled"}, want: ""},
+	}
+	for _, test := range tests {
+		if got := test.study.Identifier(); got != test.want {
+			t.Errorf("Identifier of %+v = %q, want %q", test.study, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package apihandlers
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"scholar-bot/metrics"
+)
+
+type loggerKey struct{}
+
+// ContextWithLogger returns a copy of ctx whose queries are logged with
+// logger, typically one carrying the ID of the interaction being answered.
+func ContextWithLogger(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, logger)
+}
+
+// Logger returns the logger carried by ctx, or the default logger.
+func Logger(ctx context.Context) *slog.Logger {
+	if logger, ok := ctx.Value(loggerKey{}).(*slog.Logger); ok {
+		return logger
+	}
+	return slog.Default()
+}
+
+// ErrorKind classifies err into a short label for logs and metrics.
+func ErrorKind(err error) string {
+	switch {
+	case err == nil:
+		return ""
+	case errors.Is(err, ErrNoResults):
+		return "no_results"
+	case errors.Is(err, ErrScholarBlocked):
+		return "blocked"
+	case errors.Is(err, ErrScholarCoolDown):
+		return "cool_down"
+	case errors.Is(err, ErrCircuitOpen):
+		return "circuit_open"
+	case errors.Is(err, ErrSourceDisabled):
+		return "disabled"
+	case errors.Is(err, context.DeadlineExceeded):
+		return "timeout"
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	}
+	return "error"
+}
+
+var (
+	queryDuration = metrics.NewHistogram(
+		"scholar_bot_source_query_duration_seconds",
+		"Latency of queries to each source.",
+		metrics.DefaultBuckets, "source", "operation",
+	)
+	queryErrors = metrics.NewCounter(
+		"scholar_bot_source_errors_total",
+		"Failed queries to each source by kind of error.",
+		"source", "operation", "kind",
+	)
+)
+
+// observeQuery logs and records metrics of the outcome of a query to a source.
+func observeQuery(ctx context.Context, source string, operation string, start time.Time, results int, err error) {
+	queryDuration.Observe(time.Since(start).Seconds(), source, operation)
+	if err != nil {
+		queryErrors.Inc(source, operation, ErrorKind(err))
+	}
+
+	level := slog.LevelInfo
+	if err != nil && !errors.Is(err, ErrNoResults) {
+		level = slog.LevelWarn
+	}
+	attrs := []slog.Attr{
+		slog.String("source", source),
+		slog.String("operation", operation),
+		slog.Duration("latency", time.Since(start)),
+		slog.Int("results", results),
+	}
+	if err != nil {
+		attrs = append(attrs, slog.String("error_kind", ErrorKind(err)), slog.Any("err", err))
+	}
+	Logger(ctx).LogAttrs(ctx, level, "query", attrs...)
+}
+
+func resultCount(found bool) int {
+	if found {
+		return 1
+	}
+	return 0
+}
+
+// logRequest logs an outbound HTTP request at debug level.
+func logRequest(ctx context.Context, url string, status int, start time.Time) {
+	Logger(ctx).LogAttrs(ctx, slog.LevelDebug, "http request",
+		slog.String("url", url),
+		slog.Int("status", status),
+		slog.Duration("latency", time.Since(start)),
+	)
+}
@@ -0,0 +1,40 @@
+package apihandlers
+
+import (
+	"net/http"
+	"sync/atomic"
+)
+
+// NCBICredentials identify the bot to the E-utilities. With an API key NCBI
+// allows ten requests per second instead of three.
+// https://www.ncbi.nlm.nih.gov/books/NBK25497/#chapter2.Usage_Guidelines_and_Requiremen
+type NCBICredentials struct {
+	APIKey string
+	Email  string
+	Tool   string
+}
+
+var ncbiCredentials atomic.Pointer[NCBICredentials]
+
+// SetNCBICredentials sets the credentials sent with every PubMed request.
+func SetNCBICredentials(credentials NCBICredentials) {
+	ncbiCredentials.Store(&credentials)
+}
+
+func addNCBICredentials(req *http.Request) {
+	credentials := ncbiCredentials.Load()
+	if credentials == nil {
+		return
+	}
+	params := req.URL.Query()
+	for name, value := range map[string]string{
+		"api_key": credentials.APIKey,
+		"email":   credentials.Email,
+		"tool":    credentials.Tool,
+	} {
+		if value != "" {
+			params.Set(name, value)
+		}
+	}
+	req.URL.RawQuery = params.Encode()
+}
@@ -0,0 +1,106 @@
+package apihandlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"sync/atomic"
+	"time"
+)
+
+// ErrSourceDisabled is returned without contacting a source turned off in
+// the configuration.
+var ErrSourceDisabled = errors.New("source is disabled")
+
+// HTTPClient sends every request to the sources.
+var HTTPClient = &http.Client{}
+
+// requestTimeout keeps a source that stopped answering from holding up
+// commands.
+var requestTimeout atomic.Int64
+
+// disabledSources holds the names of the sources turned off.
+var disabledSources atomic.Pointer[[]string]
+
+func init() {
+	requestTimeout.Store(int64(20 * time.Second))
+}
+
+// SetRequestTimeout changes how long a request to a source, body included,
+// may take.
+func SetRequestTimeout(timeout time.Duration) {
+	requestTimeout.Store(int64(timeout))
+}
+
+// SetEnabledSources turns off every source not in names.
+func SetEnabledSources(names []string) {
+	var disabled []string
+	for name := range Sources {
+		if !slices.Contains(names, name) {
+			disabled = append(disabled, name)
+		}
+	}
+	disabledSources.Store(&disabled)
+}
+
+// SourceEnabled reports whether the named source may be queried.
+func SourceEnabled(name string) bool {
+	disabled := disabledSources.Load()
+	return disabled == nil || !slices.Contains(*disabled, name)
+}
+
+// sendRequest sends req to the named source once its circuit breaker and
+// scheduler let it through. Closing the response body frees the request
+// slot.
+func sendRequest(ctx context.Context, source string, req *http.Request) (*http.Response, error) {
+	if !SourceEnabled(source) {
+		return nil, ErrSourceDisabled
+	}
+
+	done, err := Breakers[source].allow()
+	if err != nil {
+		return nil, err
+	}
+
+	releaseSlot, err := Schedulers[source].Acquire(ctx)
+	if err != nil {
+		done(context.Canceled)
+		return nil, err
+	}
+
+	timeoutCtx, cancel := context.WithTimeout(req.Context(), time.Duration(requestTimeout.Load()))
+	release := func() {
+		cancel()
+		releaseSlot()
+	}
+	req = req.WithContext(timeoutCtx)
+	// Logged before the credentials are added, keeping the API key out of logs
+	logURL := req.URL.String()
+	if source == "pubmed" {
+		addNCBICredentials(req)
+	}
+
+	start := time.Now()
+	resp, err := HTTPClient.Do(req)
+	if err != nil {
+		release()
+		if ctx.Err() != nil {
+			// The caller gave up, the source may be fine
+			done(context.Canceled)
+		} else {
+			done(err)
+		}
+		return nil, fmt.Errorf("error in executing the request: %w", err)
+	}
+	logRequest(ctx, logURL, resp.StatusCode, start)
+
+	if resp.StatusCode >= 500 {
+		done(errors.New(resp.Status))
+	} else {
+		done(nil)
+	}
+	resp.Body = releasingBody{ReadCloser: resp.Body, release: release}
+	return resp, nil
+}
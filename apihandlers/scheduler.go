@@ -0,0 +1,204 @@
+package apihandlers
+
+import (
+	"context"
+	"io"
+	"slices"
+	"sync"
+
+	"scholar-bot/metrics"
+)
+
+var (
+	queueDepth = metrics.NewGauge(
+		"scholar_bot_source_queue_depth",
+		"Outbound requests waiting for a free slot, per source.",
+		"source",
+	)
+	requestsRunning = metrics.NewGauge(
+		"scholar_bot_source_requests_running",
+		"Outbound requests in progress, per source.",
+		"source",
+	)
+)
+
+type queueKey struct{}
+
+type queueInfo struct {
+	owner  string
+	notice func(waiting int)
+}
+
+// ContextWithQueue returns a copy of ctx whose outbound requests queue
+// fairly against those of other owners, such as guilds. notice, when not
+// nil, is called with the number of waiting requests whenever a request
+// has to wait for a free slot.
+func ContextWithQueue(ctx context.Context, owner string, notice func(waiting int)) context.Context {
+	return context.WithValue(ctx, queueKey{}, queueInfo{owner: owner, notice: notice})
+}
+
+type waiter struct {
+	ready chan struct{}
+}
+
+// Scheduler bounds the concurrent outbound requests of a source. Waiting
+// requests are served round-robin across owners, so one busy guild can't
+// starve the others.
+type Scheduler struct {
+	source string
+
+	mu      sync.Mutex
+	limit   int
+	running int
+	waiting int
+	queues  map[string][]*waiter
+	// turns lists the owners with waiting requests, next to be served first
+	turns []string
+}
+
+// NewScheduler returns a Scheduler running up to limit requests at once.
+func NewScheduler(source string, limit int) *Scheduler {
+	return &Scheduler{source: source, limit: limit, queues: map[string][]*waiter{}}
+}
+
+// Schedulers bound the outbound requests of each source. Scholar gets a
+// single slot as bursts of requests trigger CAPTCHAs, NCBI allows three
+// requests per second without an API key.
+var Schedulers = map[string]*Scheduler{
+	"scholar": NewScheduler("scholar", 1),
+	"pubmed":  NewScheduler("pubmed", 3),
+}
+
+// SetLimit changes how many requests may run at once.
+func (s *Scheduler) SetLimit(limit int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.limit = max(limit, 1)
+	s.dispatch()
+}
+
+// Running returns the number of requests in progress.
+func (s *Scheduler) Running() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.running
+}
+
+// Waiting returns the number of queued requests.
+func (s *Scheduler) Waiting() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.waiting
+}
+
+// Acquire waits for a free slot and returns the function releasing it. It
+// gives up when ctx is done.
+func (s *Scheduler) Acquire(ctx context.Context) (func(), error) {
+	info, _ := ctx.Value(queueKey{}).(queueInfo)
+
+	s.mu.Lock()
+	if s.running < s.limit && s.waiting == 0 {
+		s.running++
+		s.updateGauges()
+		s.mu.Unlock()
+		return s.releaseFunc(), nil
+	}
+	w := &waiter{ready: make(chan struct{})}
+	if len(s.queues[info.owner]) == 0 {
+		s.turns = append(s.turns, info.owner)
+	}
+	s.queues[info.owner] = append(s.queues[info.owner], w)
+	s.waiting++
+	waiting := s.waiting
+	s.updateGauges()
+	s.mu.Unlock()
+
+	Logger(ctx).Debug("request queued", "source", s.source, "waiting", waiting)
+	if info.notice != nil {
+		info.notice(waiting)
+	}
+
+	select {
+	case <-w.ready:
+		return s.releaseFunc(), nil
+	case <-ctx.Done():
+		s.mu.Lock()
+		queued := s.removeWaiter(info.owner, w)
+		s.mu.Unlock()
+		if !queued {
+			// The slot was handed over as ctx ended, give it back
+			s.releaseFunc()()
+		}
+		return nil, ctx.Err()
+	}
+}
+
+func (s *Scheduler) releaseFunc() func() {
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			s.running--
+			s.dispatch()
+		})
+	}
+}
+
+// dispatch hands free slots to waiting requests. The caller holds mu.
+func (s *Scheduler) dispatch() {
+	for s.running < s.limit && len(s.turns) != 0 {
+		owner := s.turns[0]
+		s.turns = s.turns[1:]
+		queue := s.queues[owner]
+		w := queue[0]
+		if len(queue) > 1 {
+			s.queues[owner] = queue[1:]
+			s.turns = append(s.turns, owner)
+		} else {
+			delete(s.queues, owner)
+		}
+		s.waiting--
+		s.running++
+		close(w.ready)
+	}
+	s.updateGauges()
+}
+
+// removeWaiter takes w out of the queue, reporting whether it was still
+// queued. The caller holds mu.
+func (s *Scheduler) removeWaiter(owner string, w *waiter) bool {
+	queue := s.queues[owner]
+	i := slices.Index(queue, w)
+	if i < 0 {
+		return false
+	}
+	queue = slices.Delete(queue, i, i+1)
+	if len(queue) == 0 {
+		delete(s.queues, owner)
+		s.turns = slices.DeleteFunc(s.turns, func(turn string) bool { return turn == owner })
+	} else {
+		s.queues[owner] = queue
+	}
+	s.waiting--
+	s.updateGauges()
+	return true
+}
+
+// updateGauges exports the queue state. The caller holds mu.
+func (s *Scheduler) updateGauges() {
+	queueDepth.Set(float64(s.waiting), s.source)
+	requestsRunning.Set(float64(s.running), s.source)
+}
+
+// releasingBody gives the request slot back once the body is closed.
+type releasingBody struct {
+	io.ReadCloser
+	release func()
+}
+
+func (b releasingBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.release()
+	return err
+}
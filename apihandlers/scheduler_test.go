@@ -0,0 +1,128 @@
+package apihandlers
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// grant is a request given a slot, with the function releasing it.
+type grant struct {
+	owner   string
+	release func()
+}
+
+// queueRequest starts a request of owner on s and returns once it is
+// queued. The request is sent to granted once it gets a slot.
+func queueRequest(t *testing.T, ctx context.Context, s *Scheduler, owner string, granted chan grant) chan error {
+	t.Helper()
+	queued := make(chan struct{})
+	ctx = ContextWithQueue(ctx, owner, func(int) { close(queued) })
+	errs := make(chan error, 1)
+	go func() {
+		release, err := s.Acquire(ctx)
+		if err != nil {
+			errs <- err
+			return
+		}
+		granted <- grant{owner, release}
+	}()
+	select {
+	case <-queued:
+	case err := <-errs:
+		t.Fatalf("request of %s wasn't queued: %v", owner, err)
+	}
+	return errs
+}
+
+func TestSchedulerRoundRobin(t *testing.T) {
+	s := NewScheduler("test", 1)
+	release, err := s.Acquire(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	granted := make(chan grant, 10)
+	for _, owner := range []string{"a", "a", "a", "b", "c", "b"} {
+		queueRequest(t, context.Background(), s, owner, granted)
+	}
+	if waiting := s.Waiting(); waiting != 6 {
+		t.Fatalf("Waiting = %d, want 6", waiting)
+	}
+
+	var order []string
+	for range 6 {
+		release()
+		next := <-granted
+		order = append(order, next.owner)
+		release = next.release
+		if running := s.Running(); running != 1 {
+			t.Fatalf("Running = %d, want 1", running)
+		}
+	}
+	release()
+
+	want := []string{"a", "b", "c", "a", "b", "a"}
+	for i := range want {
+		if order[i] != want[i] {
+			t.Fatalf("served %v, want %v", order, want)
+		}
+	}
+	if running, waiting := s.Running(), s.Waiting(); running != 0 || waiting != 0 {
+		t.Errorf("Running, Waiting = %d, %d, want 0, 0", running, waiting)
+	}
+}
+
+func TestSchedulerCancelledWaiter(t *testing.T) {
+	s := NewScheduler("test", 1)
+	release, err := s.Acquire(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	granted := make(chan grant, 10)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancelled := queueRequest(t, ctx, s, "a", granted)
+	queueRequest(t, context.Background(), s, "b", granted)
+
+	cancel()
+	if err := <-cancelled; !errors.Is(err, context.Canceled) {
+		t.Fatalf("cancelled request = %v, want context.Canceled", err)
+	}
+	if waiting := s.Waiting(); waiting != 1 {
+		t.Errorf("Waiting = %d after cancelling, want 1", waiting)
+	}
+
+	release()
+	next := <-granted
+	if next.owner != "b" {
+		t.Errorf("served %s, want b", next.owner)
+	}
+	next.release()
+	// Releasing twice must not free a second slot
+	next.release()
+	if running := s.Running(); running != 0 {
+		t.Errorf("Running = %d, want 0", running)
+	}
+}
+
+func TestSchedulerSetLimit(t *testing.T) {
+	s := NewScheduler("test", 1)
+	release, err := s.Acquire(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer release()
+
+	granted := make(chan grant, 10)
+	queueRequest(t, context.Background(), s, "a", granted)
+	queueRequest(t, context.Background(), s, "b", granted)
+
+	s.SetLimit(3)
+	for range 2 {
+		(<-granted).release()
+	}
+	if waiting := s.Waiting(); waiting != 0 {
+		t.Errorf("Waiting = %d after raising the limit, want 0", waiting)
+	}
+}
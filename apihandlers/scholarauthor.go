@@ -0,0 +1,141 @@
+package apihandlers
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// AuthorProfile is a Google Scholar citations profile.
+type AuthorProfile struct {
+	Name        string
+	Affiliation string
+	Url         string
+	PhotoUrl    string
+	Interests   []string
+
+	// Metrics from the "Cited by" table, all time and since SinceYear
+	SinceYear      string
+	Citations      int
+	CitationsSince int
+	HIndex         int
+	HIndexSince    int
+	I10Index       int
+	I10IndexSince  int
+
+	Publications []StudyStruct
+}
+
+// QueryGsAuthor searches Scholar citation profiles for name and returns the
+// first matching profile.
+func QueryGsAuthor(ctx context.Context, name string) (profile *AuthorProfile, err error) {
+	defer func(start time.Time) {
+		observeQuery(ctx, "scholar", "author", start, resultCount(profile != nil), err)
+	}(time.Now())
+
+	urlQuery := fmt.Sprintf(
+		"https://scholar.google.com/citations?view_op=search_authors&hl=en&mauthors=%s",
+		url.QueryEscape(name),
+	)
+	doc, err := gsGetDocument(ctx, urlQuery)
+	if err != nil {
+		return nil, err
+	}
+
+	profileHref, ok := doc.Find(".gsc_1usr h3.gs_ai_name a").First().Attr("href")
+	if !ok {
+		if doc.Find("#gsc_sa_ccl").Length() != 0 {
+			return nil, ErrNoResults
+		}
+		startScholarCoolDown()
+		return nil, ErrScholarBlocked
+	}
+
+	profileUrl, err := url.Parse(absoluteGsUrl(profileHref))
+	if err != nil {
+		return nil, fmt.Errorf("error parsing profile url: %w", err)
+	}
+	return QueryGsAuthorProfile(ctx, profileUrl.Query().Get("user"))
+}
+
+// QueryGsAuthorProfile fetches the profile page of the Scholar user id.
+func QueryGsAuthorProfile(ctx context.Context, userId string) (*AuthorProfile, error) {
+	profileUrl := fmt.Sprintf(
+		"https://scholar.google.com/citations?hl=en&user=%s&sortby=citedby",
+		url.QueryEscape(userId),
+	)
+	doc, err := gsGetDocument(ctx, profileUrl)
+	if err != nil {
+		return nil, err
+	}
+	if doc.Find("#gsc_prf_in").Length() == 0 {
+		startScholarCoolDown()
+		return nil, ErrScholarBlocked
+	}
+
+	profile := parseGsAuthorProfile(doc)
+	profile.Url = fmt.Sprintf("https://scholar.google.com/citations?hl=en&user=%s", url.QueryEscape(userId))
+	return profile, nil
+}
+
+func parseGsAuthorProfile(doc *goquery.Document) *AuthorProfile {
+	profile := &AuthorProfile{
+		Name:        strings.TrimSpace(doc.Find("#gsc_prf_in").Text()),
+		Affiliation: strings.TrimSpace(doc.Find(".gsc_prf_il").First().Text()),
+	}
+	if photoUrl, ok := doc.Find("#gsc_prf_pup-img").Attr("src"); ok {
+		profile.PhotoUrl = absoluteGsUrl(photoUrl)
+	}
+	doc.Find("#gsc_prf_int a").Each(func(i int, interest *goquery.Selection) {
+		profile.Interests = append(profile.Interests, strings.TrimSpace(interest.Text()))
+	})
+
+	// The metrics table has a header with "All" and "Since <year>", then one
+	// row each for citations, h-index and i10-index
+	profile.SinceYear = strings.TrimPrefix(
+		strings.TrimSpace(doc.Find("#gsc_rsb_st thead th").Last().Text()), "Since ",
+	)
+	doc.Find("#gsc_rsb_st tbody tr").Each(func(i int, row *goquery.Selection) {
+		cells := row.Find("td.gsc_rsb_std")
+		all, _ := strconv.Atoi(strings.TrimSpace(cells.Eq(0).Text()))
+		since, _ := strconv.Atoi(strings.TrimSpace(cells.Eq(1).Text()))
+		switch strings.TrimSpace(row.Find(".gsc_rsb_sc1").Text()) {
+		case "Citations":
+			profile.Citations, profile.CitationsSince = all, since
+		case "h-index":
+			profile.HIndex, profile.HIndexSince = all, since
+		case "i10-index":
+			profile.I10Index, profile.I10IndexSince = all, since
+		}
+	})
+
+	doc.Find("tr.gsc_a_tr").Each(func(i int, row *goquery.Selection) {
+		titleLink := row.Find("a.gsc_a_at")
+		study := StudyStruct{
+			Title:  strings.TrimSpace(titleLink.Text()),
+			Source: "scholar",
+			Year:   strings.TrimSpace(row.Find(".gsc_a_y span").Text()),
+		}
+		if href, ok := titleLink.Attr("href"); ok {
+			study.Url = absoluteGsUrl(href)
+		}
+		details := row.Find("div.gs_gray")
+		study.Authors = strings.TrimSpace(details.Eq(0).Text())
+		study.Venue = strings.TrimSpace(details.Eq(1).Text())
+		citedBy := row.Find("a.gsc_a_ac")
+		study.CitedBy, _ = strconv.Atoi(strings.TrimSpace(citedBy.Text()))
+		if href, ok := citedBy.Attr("href"); ok && study.CitedBy != 0 {
+			study.CitedByUrl = absoluteGsUrl(href)
+		}
+		if study.Title != "" {
+			profile.Publications = append(profile.Publications, study)
+		}
+	})
+
+	return profile
+}
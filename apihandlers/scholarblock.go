@@ -0,0 +1,99 @@
+package apihandlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"scholar-bot/metrics"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+var (
+	// ErrScholarBlocked is returned when Google Scholar answers with a
+	// CAPTCHA, an "unusual traffic" page or a page we can't parse.
+	ErrScholarBlocked = errors.New("google scholar blocked the request")
+	// ErrScholarCoolDown is returned while Scholar is not queried after a block.
+	ErrScholarCoolDown = errors.New("google scholar is cooling down after a block")
+)
+
+// ScholarCoolDownPeriod is how long Scholar is left alone after it blocked us.
+var ScholarCoolDownPeriod = 30 * time.Minute
+
+var scholarCoolDown struct {
+	sync.Mutex
+	until time.Time
+}
+
+var scholarBlocks = metrics.NewCounter(
+	"scholar_bot_scholar_blocks_total",
+	"Times Google Scholar blocked the bot and a cool-down started.",
+)
+
+func init() {
+	metrics.NewGaugeFunc(
+		"scholar_bot_scholar_cool_down_seconds",
+		"Seconds until Google Scholar is queried again after a block.",
+		func() float64 { return ScholarCoolDownRemaining().Seconds() },
+	)
+}
+
+func startScholarCoolDown() {
+	scholarBlocks.Inc()
+	scholarCoolDown.Lock()
+	defer scholarCoolDown.Unlock()
+	scholarCoolDown.until = time.Now().Add(ScholarCoolDownPeriod)
+}
+
+// ScholarCoolDownRemaining returns how long until Scholar is queried again,
+// or zero when it isn't cooling down.
+func ScholarCoolDownRemaining() time.Duration {
+	scholarCoolDown.Lock()
+	defer scholarCoolDown.Unlock()
+	remaining := time.Until(scholarCoolDown.until)
+	if remaining < 0 {
+		return 0
+	}
+	return remaining
+}
+
+var gsBlockMarkers = []string{
+	"our systems have detected unusual traffic",
+	"please show you're not a robot",
+	"g-recaptcha",
+	"gs_captcha",
+}
+
+func isGsBlockedResponse(resp *http.Response) bool {
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusForbidden {
+		return true
+	}
+	// Google redirects blocked clients to its /sorry/ interstitial
+	return resp.Request != nil && strings.HasPrefix(resp.Request.URL.Path, "/sorry/")
+}
+
+func isGsBlockedDocument(doc *goquery.Document) bool {
+	if doc.Find("#gs_captcha_f, #gs_captcha_c, form#captcha-form, .g-recaptcha").Length() != 0 {
+		return true
+	}
+	// Only look for block wording when there are no results, so a paper
+	// about "unusual traffic" doesn't trip the detection
+	if doc.Find(".gs_r").Length() != 0 {
+		return false
+	}
+	body := strings.ToLower(doc.Find("body").Text())
+	for _, marker := range gsBlockMarkers {
+		if strings.Contains(body, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+// isGsEmptySearch reports whether Scholar explicitly said nothing matched.
+func isGsEmptySearch(doc *goquery.Document) bool {
+	return strings.Contains(doc.Find("#gs_res_ccl_mid, #gs_res_ccl").Text(), "did not match any articles")
+}
@@ -0,0 +1,76 @@
+package apihandlers
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// Citation formats offered by Scholar's "Cite" dialog, named as its links are.
+const (
+	GsCiteBibTeX   = "BibTeX"
+	GsCiteEndNote  = "EndNote"
+	GsCiteRefMan   = "RefMan"
+	GsCiteRefWorks = "RefWorks"
+)
+
+// GsCiteFileExtensions maps each citation format to the usual file extension.
+var GsCiteFileExtensions = map[string]string{
+	GsCiteBibTeX:   "bib",
+	GsCiteEndNote:  "enw",
+	GsCiteRefMan:   "ris",
+	GsCiteRefWorks: "txt",
+}
+
+// QueryGsCitation follows Scholar's cite link for the result with the given
+// data-cid and returns the citation text in the requested format.
+func QueryGsCitation(ctx context.Context, citeId string, format string) (citation string, err error) {
+	defer func(start time.Time) {
+		observeQuery(ctx, "scholar", "cite", start, resultCount(citation != ""), err)
+	}(time.Now())
+
+	if _, ok := GsCiteFileExtensions[format]; !ok {
+		return "", fmt.Errorf("unknown citation format %q", format)
+	}
+
+	// The cite dialog lists one export link per format
+	urlCite := fmt.Sprintf(
+		"https://scholar.google.com/scholar?q=info:%s:scholar.google.com/&output=cite&scirp=0&hl=en",
+		url.QueryEscape(citeId),
+	)
+	doc, err := gsGetDocument(ctx, urlCite)
+	if err != nil {
+		return "", err
+	}
+
+	var exportUrl string
+	doc.Find("a.gs_citi").Each(func(i int, link *goquery.Selection) {
+		if strings.TrimSpace(link.Text()) == format {
+			exportUrl, _ = link.Attr("href")
+		}
+	})
+	if exportUrl == "" {
+		return "", ErrNoResults
+	}
+
+	resp, err := gsGet(ctx, absoluteGsUrl(exportUrl))
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("error reading citation: %w", err)
+	}
+	if len(strings.TrimSpace(string(body))) == 0 {
+		return "", ErrNoResults
+	}
+
+	return string(body), nil
+}
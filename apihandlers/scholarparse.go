@@ -0,0 +1,125 @@
+package apihandlers
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+const scholarBaseUrl = "https://scholar.google.com"
+
+var (
+	gsYearRegex     = regexp.MustCompile(`\b(1[5-9]\d{2}|20\d{2})\b`)
+	gsCitedByRegex  = regexp.MustCompile(`Cited by (\d+)`)
+	gsVersionsRegex = regexp.MustCompile(`All (\d+) versions`)
+	gsTitleTagRegex = regexp.MustCompile(`^\s*\[[A-Z]+\]\s*`)
+	gsHostRegex     = regexp.MustCompile(`(?i)^([a-z0-9-]+\.)+[a-z]{2,}$`)
+)
+
+// parseGsResult turns a single ".gs_r" result block into a StudyStruct.
+func parseGsResult(s *goquery.Selection) StudyStruct {
+	study := StudyStruct{Source: "scholar"}
+	study.CiteId, _ = s.Attr("data-cid")
+
+	// Extract the title and URL, dropping the [PDF]/[BOOK]/[CITATION] tags
+	titleElem := s.Find("h3.gs_rt")
+	titleLink := titleElem.Find("a").First()
+	if titleLink.Length() != 0 {
+		study.Title = titleLink.Text()
+		study.Url, _ = titleLink.Attr("href")
+	} else {
+		study.Title = titleElem.Text()
+	}
+	study.Title = strings.TrimSpace(gsTitleTagRegex.ReplaceAllString(study.Title, ""))
+
+	// Extract authors, venue, year and publisher from the green line
+	var authorNames []string
+	authorNames, study.Venue, study.Year, study.Publisher = parseGsAuthorLine(
+		s.Find("div.gs_a").Text(),
+	)
+	study.Authors = strings.Join(authorNames, ", ")
+	for _, name := range authorNames {
+		study.AuthorList = append(study.AuthorList, parseGsAuthorName(name))
+	}
+
+	// Extract the abstract or description
+	study.Abstract = strings.TrimSpace(s.Find("div.gs_rs").Text())
+
+	// Extract the side [PDF] link if there is one
+	if pdfUrl, ok := s.Find("div.gs_ggs a").First().Attr("href"); ok {
+		study.PdfUrl = pdfUrl
+	}
+
+	// Extract cited by, related articles and versions from the footer links
+	s.Find("div.gs_fl a").Each(func(i int, link *goquery.Selection) {
+		text := strings.TrimSpace(link.Text())
+		href, _ := link.Attr("href")
+		if match := gsCitedByRegex.FindStringSubmatch(text); match != nil {
+			study.CitedBy, _ = strconv.Atoi(match[1])
+			study.CitedByUrl = absoluteGsUrl(href)
+		} else if strings.HasPrefix(text, "Related articles") {
+			study.RelatedUrl = absoluteGsUrl(href)
+		} else if match := gsVersionsRegex.FindStringSubmatch(text); match != nil {
+			study.Versions, _ = strconv.Atoi(match[1])
+			study.VersionsUrl = absoluteGsUrl(href)
+		}
+	})
+
+	return study
+}
+
+// parseGsAuthorLine splits the "authors - venue, year - publisher" line
+// shown under every Google Scholar result.
+func parseGsAuthorLine(line string) (authors []string, venue, year, publisher string) {
+	line = strings.ReplaceAll(line, "\u00a0", " ")
+	parts := strings.Split(line, " - ")
+
+	for _, author := range strings.Split(parts[0], ",") {
+		author = strings.TrimSpace(strings.Trim(author, "\u2026 "))
+		if author != "" {
+			authors = append(authors, author)
+		}
+	}
+
+	if len(parts) >= 3 {
+		publisher = strings.TrimSpace(parts[len(parts)-1])
+		parts = parts[:len(parts)-1]
+	} else if len(parts) == 2 && gsHostRegex.MatchString(strings.TrimSpace(parts[1])) {
+		// Books and preprints often only show "authors - publisher.com"
+		publisher = strings.TrimSpace(parts[1])
+		parts = parts[:1]
+	}
+	if len(parts) >= 2 {
+		venue = strings.TrimSpace(strings.Join(parts[1:], " - "))
+		// The year comes last, after numbers like arXiv identifiers
+		if locs := gsYearRegex.FindAllStringIndex(venue, -1); locs != nil {
+			loc := locs[len(locs)-1]
+			year = venue[loc[0]:loc[1]]
+			venue = strings.TrimSpace(venue[:loc[0]] + venue[loc[1]:])
+		}
+		venue = strings.Trim(venue, ", \u2026")
+	}
+
+	return authors, venue, year, publisher
+}
+
+// parseGsAuthorName splits Scholar's "JA Smith" author format.
+func parseGsAuthorName(name string) Author {
+	fields := strings.Fields(name)
+	if len(fields) < 2 {
+		return Author{LastName: name}
+	}
+	return Author{
+		LastName: fields[len(fields)-1],
+		Initials: strings.Join(fields[:len(fields)-1], ""),
+	}
+}
+
+func absoluteGsUrl(href string) string {
+	if href == "" || strings.HasPrefix(href, "http") {
+		return href
+	}
+	return scholarBaseUrl + href
+}
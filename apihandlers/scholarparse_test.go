@@ -0,0 +1,106 @@
+package apihandlers
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseGsAuthorLine(t *testing.T) {
+	tests := []struct {
+		line      string
+		authors   []string
+		venue     string
+		year      string
+		publisher string
+	}{
+		{
+			line:      "JA Smith, B Jones - Nature, 2019 - nature.com",
+			authors:   []string{"JA Smith", "B Jones"},
+			venue:     "Nature",
+			year:      "2019",
+			publisher: "nature.com",
+		},
+		{
+			line:    "A Vaswani, N Shazeer… - Advances in neural information processing systems, 2017",
+			authors: []string{"A Vaswani", "N Shazeer"},
+			venue:   "Advances in neural information processing systems",
+			year:    "2017",
+		},
+		{
+			line:    "A Vaswani - arXiv preprint arXiv:1706.03762, 2017",
+			authors: []string{"A Vaswani"},
+			venue:   "arXiv preprint arXiv:1706.03762",
+			year:    "2017",
+		},
+		{
+			line:      "JA Smith - books.google.com",
+			authors:   []string{"JA Smith"},
+			publisher: "books.google.com",
+		},
+		{
+			line:    "JA Smith - 2015",
+			authors: []string{"JA Smith"},
+			year:    "2015",
+		},
+		{
+			line:    "JA Smith - The Lancet",
+			authors: []string{"JA Smith"},
+			venue:   "The Lancet",
+		},
+		{
+			line:      "JA Smith - Journal of Things - Part B, 2020 - Elsevier",
+			authors:   []string{"JA Smith"},
+			venue:     "Journal of Things - Part B",
+			year:      "2020",
+			publisher: "Elsevier",
+		},
+		{
+			line:    "JA Smith",
+			authors: []string{"JA Smith"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.line, func(t *testing.T) {
+			authors, venue, year, publisher := parseGsAuthorLine(test.line)
+			if !reflect.DeepEqual(authors, test.authors) {
+				t.Errorf("authors = %q, want %q", authors, test.authors)
+			}
+			if venue != test.venue || year != test.year || publisher != test.publisher {
+				t.Errorf("venue, year, publisher = %q, %q, %q, want %q, %q, %q",
+					venue, year, publisher, test.venue, test.year, test.publisher)
+			}
+		})
+	}
+}
+
+func TestParseGsAuthorName(t *testing.T) {
+	tests := []struct {
+		name string
+		want Author
+	}{
+		{name: "JA Smith", want: Author{LastName: "Smith", Initials: "JA"}},
+		{name: "J A Smith", want: Author{LastName: "Smith", Initials: "JA"}},
+		{name: "Smith", want: Author{LastName: "Smith"}},
+	}
+	for _, test := range tests {
+		if got := parseGsAuthorName(test.name); got != test.want {
+			t.Errorf("parseGsAuthorName(%q) = %+v, want %+v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestAuthorGivenName(t *testing.T) {
+	tests := []struct {
+		author Author
+		want   string
+	}{
+		{author: Author{LastName: "Smith", ForeName: "John Adam", Initials: "JA"}, want: "John Adam"},
+		{author: Author{LastName: "Smith", Initials: "JA"}, want: "J. A."},
+		{author: Author{LastName: "Smith"}, want: ""},
+	}
+	for _, test := range tests {
+		if got := test.author.GivenName(); got != test.want {
+			t.Errorf("GivenName of %+v = %q, want %q", test.author, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,206 @@
+package apihandlers
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"scholar-bot/cache"
+	"scholar-bot/metrics"
+)
+
+var cacheRequests = metrics.NewCounter(
+	"scholar_bot_cache_requests_total",
+	"Source queries answered from the cache (hit), by another identical query in flight (shared) or upstream (miss).",
+	"source", "result",
+)
+
+type cachedSource struct {
+	Source
+	key      string
+	cache    *cache.Cache[[]StudyStruct]
+	ttl      func() time.Duration
+	inFlight *cache.Group[[]StudyStruct]
+	notices  *sharedNotices
+}
+
+// sharedNotices forwards the queue notices of a query running for several
+// callers to each of them, so every waiting interaction is deferred, not
+// only the one whose query runs.
+type sharedNotices struct {
+	mu      sync.Mutex
+	flights map[string]*flight
+}
+
+type flight struct {
+	notices map[*noticeEntry]struct{}
+	// waiting is the queue length last reported, 0 while not queued
+	waiting int
+}
+
+type noticeEntry struct {
+	notice func(waiting int)
+}
+
+// join registers the queue notice of ctx for the query key and returns the
+// function unregistering it. When the query is already queued, the notice
+// is called at once.
+func (n *sharedNotices) join(ctx context.Context, key string) func() {
+	info, _ := ctx.Value(queueKey{}).(queueInfo)
+	if info.notice == nil {
+		return func() {}
+	}
+	entry := &noticeEntry{notice: info.notice}
+
+	n.mu.Lock()
+	if n.flights == nil {
+		n.flights = map[string]*flight{}
+	}
+	f := n.flights[key]
+	if f == nil {
+		f = &flight{notices: map[*noticeEntry]struct{}{}}
+		n.flights[key] = f
+	}
+	f.notices[entry] = struct{}{}
+	waiting := f.waiting
+	n.mu.Unlock()
+
+	if waiting > 0 {
+		entry.notice(waiting)
+	}
+	return func() {
+		n.mu.Lock()
+		defer n.mu.Unlock()
+		delete(f.notices, entry)
+		if len(f.notices) == 0 && n.flights[key] == f {
+			delete(n.flights, key)
+		}
+	}
+}
+
+// context returns the context the query key runs with: it queues as ctx
+// does, but its notices go to every caller joined to the query.
+func (n *sharedNotices) context(ctx context.Context, key string) context.Context {
+	info, _ := ctx.Value(queueKey{}).(queueInfo)
+	n.mu.Lock()
+	if f := n.flights[key]; f != nil {
+		f.waiting = 0
+	}
+	n.mu.Unlock()
+	return ContextWithQueue(ctx, info.owner, func(waiting int) {
+		n.notify(key, waiting)
+	})
+}
+
+func (n *sharedNotices) notify(key string, waiting int) {
+	n.mu.Lock()
+	f := n.flights[key]
+	if f == nil {
+		n.mu.Unlock()
+		return
+	}
+	f.waiting = waiting
+	notices := make([]func(int), 0, len(f.notices))
+	for entry := range f.notices {
+		notices = append(notices, entry.notice)
+	}
+	n.mu.Unlock()
+
+	for _, notice := range notices {
+		notice(waiting)
+	}
+}
+
+// WithCache returns a Source answering repeated queries from c for the
+// duration returned by ttl and running concurrent identical queries only
+// once. key names the source in cache keys and metrics. Queries skip the
+// cache while ttl returns zero.
+func WithCache(source Source, key string, c *cache.Cache[[]StudyStruct], ttl func() time.Duration) Source {
+	if c == nil {
+		return source
+	}
+	return cachedSource{
+		Source:   source,
+		key:      key,
+		cache:    c,
+		ttl:      ttl,
+		inFlight: &cache.Group[[]StudyStruct]{},
+		notices:  &sharedNotices{},
+	}
+}
+
+// CacheKey identifies a query to a source, ignoring case and extra spaces.
+func CacheKey(source string, operation string, query string, minYear string) string {
+	query = strings.ToLower(strings.Join(strings.Fields(query), " "))
+	return strings.Join([]string{source, operation, minYear, query}, "|")
+}
+
+func (s cachedSource) lookup(ctx context.Context, operation string, query string, minYear string, fetch func(ctx context.Context) ([]StudyStruct, error)) ([]StudyStruct, error) {
+	ttl := s.ttl()
+	if ttl <= 0 {
+		return fetch(ctx)
+	}
+
+	key := CacheKey(s.key, operation, query, minYear)
+	if studies, ok := s.cache.Get(key); ok {
+		cacheRequests.Inc(s.key, "hit")
+		Logger(ctx).Debug("cache hit", "source", s.key, "operation", operation)
+		return studies, nil
+	}
+
+	leave := s.notices.join(ctx, key)
+	defer leave()
+	run := func() ([]StudyStruct, error) {
+		studies, err := fetch(s.notices.context(ctx, key))
+		if err == nil {
+			s.cache.Set(key, studies, ttl)
+		}
+		return studies, err
+	}
+	studies, err, shared := s.inFlight.Do(ctx, key, run)
+	if shared && errors.Is(err, context.Canceled) && ctx.Err() == nil {
+		// The call we waited for was cancelled with its caller's context,
+		// which isn't ours, so try again
+		Logger(ctx).Debug("shared query cancelled, retrying", "source", s.key, "operation", operation)
+		studies, err, shared = s.inFlight.Do(ctx, key, run)
+	}
+	if shared {
+		cacheRequests.Inc(s.key, "shared")
+	} else {
+		cacheRequests.Inc(s.key, "miss")
+	}
+	return studies, err
+}
+
+func (s cachedSource) QueryFirst(ctx context.Context, query string, minYear string) (*StudyStruct, error) {
+	studies, err := s.lookup(ctx, "first", query, minYear, func(ctx context.Context) ([]StudyStruct, error) {
+		study, err := s.Source.QueryFirst(ctx, query, minYear)
+		if err != nil {
+			return nil, err
+		}
+		return []StudyStruct{*study}, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	study := studies[0]
+	return &study, nil
+}
+
+func (s cachedSource) QueryTopTen(ctx context.Context, query string, minYear string) (*[]StudyStruct, error) {
+	studies, err := s.lookup(ctx, "top_ten", query, minYear, func(ctx context.Context) ([]StudyStruct, error) {
+		studies, err := s.Source.QueryTopTen(ctx, query, minYear)
+		if err != nil {
+			return nil, err
+		}
+		return *studies, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	// Callers may reorder or trim the slice, keep the cached one intact
+	studies = append([]StudyStruct(nil), studies...)
+	return &studies, nil
+}
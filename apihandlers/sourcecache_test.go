@@ -0,0 +1,165 @@
+package apihandlers
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"scholar-bot/cache"
+)
+
+// blockingSource answers once release is closed, or fails when the
+// caller's context is cancelled first. The first call reports the queue
+// length queued to its context's notice, as a request waiting for a slot
+// would.
+type blockingSource struct {
+	started chan struct{}
+	release chan struct{}
+	queued  int
+	calls   atomic.Int32
+}
+
+func newBlockingSource() *blockingSource {
+	return &blockingSource{started: make(chan struct{}), release: make(chan struct{})}
+}
+
+func (s *blockingSource) Name() string { return "test" }
+
+func (s *blockingSource) QueryFirst(ctx context.Context, query string, minYear string) (*StudyStruct, error) {
+	if s.calls.Add(1) == 1 {
+		if info, _ := ctx.Value(queueKey{}).(queueInfo); s.queued > 0 && info.notice != nil {
+			info.notice(s.queued)
+		}
+		close(s.started)
+	}
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-s.release:
+		return &StudyStruct{Title: query}, nil
+	}
+}
+
+func (s *blockingSource) QueryTopTen(ctx context.Context, query string, minYear string) (*[]StudyStruct, error) {
+	study, err := s.QueryFirst(ctx, query, minYear)
+	if err != nil {
+		return nil, err
+	}
+	return &[]StudyStruct{*study}, nil
+}
+
+type queryResult struct {
+	study *StudyStruct
+	err   error
+}
+
+// newTestCachedSource returns a cached upstream and a channel receiving a
+// value whenever a query joins another one in flight.
+func newTestCachedSource(upstream Source) (Source, chan struct{}) {
+	source := WithCache(upstream, "test", cache.New[[]StudyStruct](10), func() time.Duration { return time.Hour })
+	joined := make(chan struct{}, 10)
+	source.(cachedSource).inFlight.Joined = func(string) { joined <- struct{}{} }
+	return source, joined
+}
+
+func queryAsync(ctx context.Context, source Source) chan queryResult {
+	result := make(chan queryResult, 1)
+	go func() {
+		study, err := source.QueryFirst(ctx, "query", "2015")
+		result <- queryResult{study, err}
+	}()
+	return result
+}
+
+func TestCachedSourceSharedCancellation(t *testing.T) {
+	upstream := newBlockingSource()
+	source, joined := newTestCachedSource(upstream)
+
+	firstCtx, cancelFirst := context.WithCancel(context.Background())
+	first := queryAsync(firstCtx, source)
+	<-upstream.started
+
+	second := queryAsync(context.Background(), source)
+	<-joined
+	cancelFirst()
+	if got := <-first; got.err == nil {
+		t.Fatal("the cancelled query succeeded")
+	}
+
+	close(upstream.release)
+	got := <-second
+	if got.err != nil {
+		t.Fatalf("the query sharing a cancelled call failed: %v", got.err)
+	}
+	if got.study.Title != "query" {
+		t.Errorf("Title = %q, want query", got.study.Title)
+	}
+	if calls := upstream.calls.Load(); calls != 2 {
+		t.Errorf("upstream called %d times, want 2", calls)
+	}
+}
+
+func TestCachedSourceSharedWaitEndsWithContext(t *testing.T) {
+	upstream := newBlockingSource()
+	source, joined := newTestCachedSource(upstream)
+	defer close(upstream.release)
+
+	first := queryAsync(context.Background(), source)
+	<-upstream.started
+
+	secondCtx, cancelSecond := context.WithCancel(context.Background())
+	second := queryAsync(secondCtx, source)
+	<-joined
+	cancelSecond()
+	if got := <-second; !errors.Is(got.err, context.Canceled) {
+		t.Errorf("the cancelled waiting query returned %v, want context.Canceled", got.err)
+	}
+
+	select {
+	case got := <-first:
+		t.Fatalf("the running query ended early: %v", got.err)
+	default:
+	}
+}
+
+func TestCachedSourceSharedQueueNotice(t *testing.T) {
+	upstream := newBlockingSource()
+	upstream.queued = 3
+	source, joined := newTestCachedSource(upstream)
+
+	notices := make(chan string, 10)
+	queueCtx := func(owner string) context.Context {
+		return ContextWithQueue(context.Background(), owner, func(waiting int) {
+			if waiting != 3 {
+				t.Errorf("%s notified of %d waiting, want 3", owner, waiting)
+			}
+			notices <- owner
+		})
+	}
+
+	first := queryAsync(queueCtx("first"), source)
+	<-upstream.started
+	if owner := <-notices; owner != "first" {
+		t.Fatalf("notice went to %s, want first", owner)
+	}
+
+	second := queryAsync(queueCtx("second"), source)
+	<-joined
+	select {
+	case owner := <-notices:
+		if owner != "second" {
+			t.Errorf("notice went to %s, want second", owner)
+		}
+	default:
+		t.Error("the query joining a queued one wasn't notified")
+	}
+
+	close(upstream.release)
+	for _, result := range []chan queryResult{first, second} {
+		if got := <-result; got.err != nil {
+			t.Fatal(got.err)
+		}
+	}
+}
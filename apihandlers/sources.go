@@ -0,0 +1,136 @@
+package apihandlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Source is a search backend the bot can query for studies.
+type Source interface {
+	Name() string
+	QueryFirst(ctx context.Context, query string, minYear string) (*StudyStruct, error)
+	QueryTopTen(ctx context.Context, query string, minYear string) (*[]StudyStruct, error)
+}
+
+type scholarSource struct{}
+
+func (scholarSource) Name() string { return "Google Scholar" }
+
+func (scholarSource) QueryFirst(ctx context.Context, query string, minYear string) (*StudyStruct, error) {
+	return QueryFirstGs(ctx, query, minYear)
+}
+
+func (scholarSource) QueryTopTen(ctx context.Context, query string, minYear string) (*[]StudyStruct, error) {
+	return QueryTopTenGs(ctx, query, minYear)
+}
+
+type pubmedSource struct{}
+
+func (pubmedSource) Name() string { return "PubMed" }
+
+func (pubmedSource) QueryFirst(ctx context.Context, query string, minYear string) (*StudyStruct, error) {
+	return QueryFirstPMC(ctx, query, minYear)
+}
+
+func (pubmedSource) QueryTopTen(ctx context.Context, query string, minYear string) (*[]StudyStruct, error) {
+	return QueryTopTenPMC(ctx, query, minYear)
+}
+
+// Sources holds every available backend keyed by its config name.
+var Sources = map[string]Source{
+	"scholar": observedSource{Source: scholarSource{}, key: "scholar"},
+	"pubmed":  observedSource{Source: pubmedSource{}, key: "pubmed"},
+}
+
+// observedSource logs the latency, result count and error kind of every
+// query made to the wrapped Source.
+type observedSource struct {
+	Source
+	key string
+}
+
+func (s observedSource) QueryFirst(ctx context.Context, query string, minYear string) (*StudyStruct, error) {
+	start := time.Now()
+	study, err := s.Source.QueryFirst(ctx, query, minYear)
+	observeQuery(ctx, s.key, "first", start, resultCount(study != nil), err)
+	return study, err
+}
+
+func (s observedSource) QueryTopTen(ctx context.Context, query string, minYear string) (*[]StudyStruct, error) {
+	start := time.Now()
+	studies, err := s.Source.QueryTopTen(ctx, query, minYear)
+	results := 0
+	if studies != nil {
+		results = len(*studies)
+	}
+	observeQuery(ctx, s.key, "top_ten", start, results, err)
+	return studies, err
+}
+
+// IsUnavailable reports whether err means the source can't be used right
+// now, as opposed to the query simply having no results.
+func IsUnavailable(err error) bool {
+	return errors.Is(err, ErrScholarBlocked) ||
+		errors.Is(err, ErrScholarCoolDown) ||
+		errors.Is(err, ErrCircuitOpen) ||
+		errors.Is(err, ErrSourceDisabled)
+}
+
+type fallbackSource struct {
+	Source
+	fallback Source
+}
+
+// WithFallback returns a Source that queries fallback whenever primary is
+// unavailable. The returned studies carry the Source they came from.
+func WithFallback(primary Source, fallback Source) Source {
+	if fallback == nil {
+		return primary
+	}
+	return fallbackSource{Source: primary, fallback: fallback}
+}
+
+func (s fallbackSource) QueryFirst(ctx context.Context, query string, minYear string) (*StudyStruct, error) {
+	study, err := s.Source.QueryFirst(ctx, query, minYear)
+	if IsUnavailable(err) {
+		Logger(ctx).Warn("source unavailable, falling back",
+			"source", s.Source.Name(), "fallback", s.fallback.Name(), "error_kind", ErrorKind(err))
+		return s.fallback.QueryFirst(ctx, query, minYear)
+	}
+	return study, err
+}
+
+func (s fallbackSource) QueryTopTen(ctx context.Context, query string, minYear string) (*[]StudyStruct, error) {
+	studies, err := s.Source.QueryTopTen(ctx, query, minYear)
+	if IsUnavailable(err) {
+		Logger(ctx).Warn("source unavailable, falling back",
+			"source", s.Source.Name(), "fallback", s.fallback.Name(), "error_kind", ErrorKind(err))
+		return s.fallback.QueryTopTen(ctx, query, minYear)
+	}
+	return studies, err
+}
+
+// QueryRecent returns the newest studies of the named source for query.
+// PubMed is restricted to entries added since since; Scholar can only sort
+// by date, so callers must skip results they have already seen.
+func QueryRecent(ctx context.Context, sourceName string, query string, since time.Time) (*[]StudyStruct, error) {
+	var studies *[]StudyStruct
+	var err error
+	start := time.Now()
+	switch sourceName {
+	case "pubmed":
+		studies, err = QueryRecentPMC(ctx, query, since)
+	case "scholar":
+		studies, err = QueryRecentGs(ctx, query)
+	default:
+		return nil, fmt.Errorf("unknown source %q", sourceName)
+	}
+	results := 0
+	if studies != nil {
+		results = len(*studies)
+	}
+	observeQuery(ctx, sourceName, "recent", start, results, err)
+	return studies, err
+}
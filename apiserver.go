@@ -0,0 +1,372 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"scholar-bot/apihandlers"
+	"scholar-bot/exporters"
+	"scholar-bot/metrics"
+)
+
+// apiRequestTimeout bounds an API request, queueing included.
+const apiRequestTimeout = 2 * time.Minute
+
+// apiMaxIdentifiers bounds how many studies one export looks up.
+const apiMaxIdentifiers = 50
+
+var apiRequests = metrics.NewCounter(
+	"scholar_bot_api_requests_total",
+	"Requests to the search API, by endpoint and status code.",
+	"endpoint", "code",
+)
+
+// apiParam documents a parameter of an API endpoint.
+type apiParam struct {
+	Name        string
+	In          string
+	Description string
+	Type        string
+	Enum        []string
+	Required    bool
+}
+
+// apiEndpoint is a route of the API. The OpenAPI document is generated
+// from the endpoints.
+type apiEndpoint struct {
+	Path    string
+	Name    string
+	Summary string
+	Params  []apiParam
+	// Result names the schema of the JSON response, empty when the
+	// endpoint returns an export file
+	Result  string
+	Handler func(w http.ResponseWriter, r *http.Request) error
+}
+
+// apiError is an error answered with its status code.
+type apiError struct {
+	Status  int
+	Message string
+}
+
+func (e apiError) Error() string { return e.Message }
+
+// SearchResult is the response of /v1/search.
+type SearchResult struct {
+	// Source is the source the studies came from, which differs from the
+	// requested one when Google Scholar fell back to another source
+	Source  string
+	Studies []apihandlers.StudyStruct
+}
+
+var searchParams = []apiParam{
+	{Name: "q", In: "query", Description: "Search terms", Type: "string", Required: true},
+	{Name: "source", In: "query", Description: "Source to search (default pubmed)", Type: "string", Enum: []string{"pubmed", "scholar"}},
+	{Name: "min_year", In: "query", Description: fmt.Sprintf("Only studies published since this year (default %d)", defaultMinYear), Type: "integer"},
+	{Name: "limit", In: "query", Description: "Number of studies, from 1 to 10 (default 10)", Type: "integer"},
+}
+
+var apiEndpoints = []apiEndpoint{
+	{
+		Path:    "/v1/search",
+		Name:    "search",
+		Summary: "Search a source",
+		Params:  searchParams,
+		Result:  "SearchResult",
+		Handler: apiSearchHandler,
+	},
+	{
+		Path:    "/v1/paper/{id...}",
+		Name:    "paper",
+		Summary: "Look a study up by PMID, DOI or Google Scholar id",
+		Params: []apiParam{
+			{Name: "id", In: "path", Description: "pmid:<PMID>, doi:<DOI>, gs:<Scholar id>, or a bare PMID or DOI", Type: "string", Required: true},
+		},
+		Result:  "Study",
+		Handler: apiPaperHandler,
+	},
+	{
+		Path:    "/v1/export",
+		Name:    "export",
+		Summary: "Export studies, looked up by identifier or searched for, to a reference manager format",
+		Params: append([]apiParam{
+			{Name: "format", In: "query", Description: "Export format (default bibtex)", Type: "string", Enum: ExportFormatNamesHelper()},
+			{Name: "ids", In: "query", Description: fmt.Sprintf("Comma-separated identifiers, up to %d, instead of a search", apiMaxIdentifiers), Type: "string"},
+		}, optionalParamsHelper(searchParams)...),
+		Handler: apiExportHandler,
+	},
+}
+
+func optionalParamsHelper(params []apiParam) []apiParam {
+	optional := make([]apiParam, len(params))
+	for i, param := range params {
+		param.Required = false
+		optional[i] = param
+	}
+	return optional
+}
+
+// StartAPIServer serves the search API on addr in the background.
+func StartAPIServer(addr string) *http.Server {
+	mux := http.NewServeMux()
+	for _, endpoint := range apiEndpoints {
+		mux.Handle("GET "+endpoint.Path, apiHandler(endpoint))
+	}
+	mux.HandleFunc("GET /v1/openapi.json", func(w http.ResponseWriter, r *http.Request) {
+		writeJSONHelper(w, http.StatusOK, OpenAPIHelper())
+	})
+	server := &http.Server{
+		Addr:              addr,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		slog.Info("serving the search API", "addr", addr)
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			slog.Error("API server stopped", "err", err)
+		}
+	}()
+	return server
+}
+
+// apiHandler checks the API key and runs the endpoint as work shutdown
+// waits for, queueing its requests to the sources like a guild's.
+func apiHandler(endpoint apiEndpoint) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		defer func() {
+			apiRequests.Inc(endpoint.Name, strconv.Itoa(recorder.status))
+		}()
+
+		if !APIKeyValidHelper(r) {
+			recorder.Header().Set("WWW-Authenticate", `Bearer realm="scholar-bot"`)
+			writeJSONHelper(recorder, http.StatusUnauthorized, map[string]string{"error": "missing or invalid API key"})
+			return
+		}
+		done, ok := StartWorkHelper()
+		if !ok {
+			writeJSONHelper(recorder, http.StatusServiceUnavailable, map[string]string{"error": "the bot is shutting down"})
+			return
+		}
+		defer done()
+
+		ctx, cancel := context.WithTimeout(r.Context(), apiRequestTimeout)
+		defer cancel()
+		stopOnShutdown := context.AfterFunc(workCtx, cancel)
+		defer stopOnShutdown()
+		ctx = apihandlers.ContextWithLogger(ctx, slog.With("api", endpoint.Name))
+		ctx = apihandlers.ContextWithQueue(ctx, "api", nil)
+
+		start := time.Now()
+		err := endpoint.Handler(recorder, r.WithContext(ctx))
+		if err != nil {
+			var requestErr apiError
+			if !errors.As(err, &requestErr) {
+				requestErr = apiError{Status: http.StatusInternalServerError, Message: "internal error"}
+				apihandlers.Logger(ctx).Error("API request failed", "err", err)
+			}
+			writeJSONHelper(recorder, requestErr.Status, map[string]string{"error": requestErr.Message})
+		}
+		apihandlers.Logger(ctx).Info("API request", "path", r.URL.Path, "status", recorder.status, "latency", time.Since(start))
+	})
+}
+
+// APIKeyValidHelper reports whether the request carries one of the API
+// keys, in an X-API-Key or a bearer Authorization header.
+func APIKeyValidHelper(r *http.Request) bool {
+	key := r.Header.Get("X-API-Key")
+	if bearer, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		key = bearer
+	}
+	if key == "" {
+		return false
+	}
+	valid := false
+	for _, accepted := range CurrentConfigHelper().HTTP.APIKeyList() {
+		if subtle.ConstantTimeCompare([]byte(key), []byte(accepted)) == 1 {
+			valid = true
+		}
+	}
+	return valid
+}
+
+// sourceErrorHelper turns a source error into the API's answer.
+func sourceErrorHelper(err error, sourceName string) error {
+	status := http.StatusBadGateway
+	switch {
+	case errors.Is(err, apihandlers.ErrNoResults):
+		status = http.StatusNotFound
+	case apihandlers.IsUnavailable(err):
+		status = http.StatusServiceUnavailable
+	case errors.Is(err, context.DeadlineExceeded):
+		status = http.StatusGatewayTimeout
+	}
+	return apiError{Status: status, Message: ErrorMessageHelper(err, sourceName)}
+}
+
+// apiSearchHelper runs the search described by the query parameters.
+func apiSearchHelper(r *http.Request) (SearchResult, error) {
+	query := strings.TrimSpace(r.FormValue("q"))
+	if query == "" {
+		return SearchResult{}, apiError{Status: http.StatusBadRequest, Message: "q is required"}
+	}
+	sourceName := r.FormValue("source")
+	if sourceName == "" {
+		sourceName = "pubmed"
+	}
+	source, ok := apihandlers.Sources[sourceName]
+	if !ok {
+		return SearchResult{}, apiError{Status: http.StatusBadRequest, Message: fmt.Sprintf("unknown source %q", sourceName)}
+	}
+	if sourceName == "scholar" {
+		source = ScholarSourceHelper()
+	}
+	minYear, err := intParamHelper(r, "min_year", defaultMinYear, 0, 9999)
+	if err != nil {
+		return SearchResult{}, err
+	}
+	limit, err := intParamHelper(r, "limit", 10, 1, 10)
+	if err != nil {
+		return SearchResult{}, err
+	}
+
+	var studies []apihandlers.StudyStruct
+	if limit == 1 {
+		var study *apihandlers.StudyStruct
+		study, err = source.QueryFirst(r.Context(), query, fmt.Sprint(minYear))
+		if err == nil {
+			studies = []apihandlers.StudyStruct{*study}
+		}
+	} else {
+		var studySlice *[]apihandlers.StudyStruct
+		studySlice, err = source.QueryTopTen(r.Context(), query, fmt.Sprint(minYear))
+		if err == nil {
+			studies = *studySlice
+		}
+	}
+	if err != nil {
+		return SearchResult{}, sourceErrorHelper(err, source.Name())
+	}
+	if len(studies) > limit {
+		studies = studies[:limit]
+	}
+
+	resultSource := studies[0].Source
+	if resultSource == "" {
+		resultSource = sourceName
+	}
+	return SearchResult{Source: resultSource, Studies: studies}, nil
+}
+
+func apiSearchHandler(w http.ResponseWriter, r *http.Request) error {
+	result, err := apiSearchHelper(r)
+	if err != nil {
+		return err
+	}
+	writeJSONHelper(w, http.StatusOK, result)
+	return nil
+}
+
+// apiLookupHelper looks a study up by the identifier given by a user.
+func apiLookupHelper(ctx context.Context, input string) (*apihandlers.StudyStruct, error) {
+	identifier, err := apihandlers.ParseIdentifier(input)
+	if err != nil {
+		return nil, apiError{Status: http.StatusBadRequest, Message: err.Error()}
+	}
+	study, err := apihandlers.QueryByIdentifier(ctx, identifier)
+	if err != nil {
+		return nil, sourceErrorHelper(err, IdentifierSourceHelper(identifier))
+	}
+	return study, nil
+}
+
+func apiPaperHandler(w http.ResponseWriter, r *http.Request) error {
+	study, err := apiLookupHelper(r.Context(), r.PathValue("id"))
+	if err != nil {
+		return err
+	}
+	writeJSONHelper(w, http.StatusOK, study)
+	return nil
+}
+
+func apiExportHandler(w http.ResponseWriter, r *http.Request) error {
+	formatName := r.FormValue("format")
+	if formatName == "" {
+		formatName = "bibtex"
+	}
+	format, ok := exporters.Formats[formatName]
+	if !ok {
+		return apiError{Status: http.StatusBadRequest, Message: fmt.Sprintf("unknown format %q", formatName)}
+	}
+
+	var studies []apihandlers.StudyStruct
+	if ids := r.FormValue("ids"); ids != "" {
+		inputs := strings.Split(ids, ",")
+		if len(inputs) > apiMaxIdentifiers {
+			return apiError{Status: http.StatusBadRequest, Message: fmt.Sprintf("at most %d ids can be exported at once", apiMaxIdentifiers)}
+		}
+		for _, input := range inputs {
+			study, err := apiLookupHelper(r.Context(), input)
+			if err != nil {
+				return err
+			}
+			studies = append(studies, *study)
+		}
+	} else {
+		result, err := apiSearchHelper(r)
+		if err != nil {
+			return err
+		}
+		studies = result.Studies
+	}
+
+	content, err := format.Export(studies)
+	if err != nil {
+		return err
+	}
+	w.Header().Set("Content-Type", format.ContentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="studies.%s"`, format.Extension))
+	w.Write([]byte(content))
+	return nil
+}
+
+// intParamHelper reads an optional integer query parameter.
+func intParamHelper(r *http.Request, name string, fallback int, min int, max int) (int, error) {
+	value := r.FormValue(name)
+	if value == "" {
+		return fallback, nil
+	}
+	number, err := strconv.Atoi(value)
+	if err != nil || number < min || number > max {
+		return 0, apiError{Status: http.StatusBadRequest, Message: fmt.Sprintf("%s must be a number from %d to %d", name, min, max)}
+	}
+	return number, nil
+}
+
+func writeJSONHelper(w http.ResponseWriter, status int, value any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(value)
+}
+
+// statusRecorder remembers the status code of a response for metrics.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
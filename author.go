@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"scholar-bot/apihandlers"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func AuthorEmbedHelper(profile *apihandlers.AuthorProfile) *discordgo.MessageEmbed {
+	embed := &discordgo.MessageEmbed{
+		Title:       profile.Name,
+		URL:         profile.Url,
+		Description: profile.Affiliation,
+	}
+	if profile.PhotoUrl != "" {
+		embed.Thumbnail = &discordgo.MessageEmbedThumbnail{URL: profile.PhotoUrl}
+	}
+
+	if len(profile.Interests) != 0 {
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
+			Name: "Interests", Value: strings.Join(profile.Interests, ", "),
+		})
+	}
+
+	since := "Recent"
+	if profile.SinceYear != "" {
+		since = "Since " + profile.SinceYear
+	}
+	for _, metric := range []struct {
+		name       string
+		all, since int
+	}{
+		{"Citations", profile.Citations, profile.CitationsSince},
+		{"h-index", profile.HIndex, profile.HIndexSince},
+		{"i10-index", profile.I10Index, profile.I10IndexSince},
+	} {
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
+			Name:   metric.name,
+			Value:  fmt.Sprintf("All: %d\n%s: %d", metric.all, since, metric.since),
+			Inline: true,
+		})
+	}
+
+	if len(profile.Publications) != 0 {
+		var publications string
+		for i, publication := range profile.Publications {
+			if i == 5 {
+				break
+			}
+			line := fmt.Sprintf("- [%s](%s)", publication.Title, publication.Url)
+			if publication.Year != "" {
+				line += fmt.Sprintf(" (%s, cited by %d)", publication.Year, publication.CitedBy)
+			}
+			// Embed field values are capped at 1024 characters
+			if len(publications)+len(line)+1 > 1024 {
+				break
+			}
+			publications += line + "\n"
+		}
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
+			Name: "Top publications", Value: publications,
+		})
+	}
+
+	return embed
+}
+
+func AuthorCommandHandler(ctx context.Context, botSession *discordgo.Session, botInteraction *discordgo.InteractionCreate) {
+	options := botInteraction.ApplicationCommandData().Options
+	optionMap := make(
+		map[string]*discordgo.ApplicationCommandInteractionDataOption,
+		len(options),
+	)
+	for _, opt := range options {
+		optionMap[opt.Name] = opt
+	}
+
+	name, ok := optionMap["name"]
+	if !ok {
+		RespondHelper(ctx, botSession, botInteraction,
+			&discordgo.InteractionResponse{
+				Type: discordgo.InteractionResponseChannelMessageWithSource,
+				Data: &discordgo.InteractionResponseData{
+					Content: "An error happened when retrieving the query",
+					Flags:   1 << 6,
+				},
+			})
+		return
+	}
+
+	profile, err := apihandlers.QueryGsAuthor(ctx, name.StringValue())
+	if err != nil {
+		RespondHelper(ctx, botSession, botInteraction,
+			&discordgo.InteractionResponse{
+				Type: discordgo.InteractionResponseChannelMessageWithSource,
+				Data: &discordgo.InteractionResponseData{
+					Content: ErrorMessageHelper(err, "Google Scholar"),
+					Flags:   1 << 6,
+				},
+			})
+		return
+	}
+
+	RespondHelper(ctx, botSession, botInteraction,
+		&discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{
+				Embeds: []*discordgo.MessageEmbed{AuthorEmbedHelper(profile)},
+				Flags:  ResultFlagsHelper(GuildSettingsHelper(botInteraction.GuildID)),
+			},
+		})
+}
@@ -0,0 +1,137 @@
+// Package cache provides an in-memory LRU cache with per-entry expiry,
+// optionally persisted through a Backing, and request coalescing.
+package cache
+
+import (
+	"container/list"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// Entry is a cached value and when it stops being fresh.
+type Entry[V any] struct {
+	Value     V
+	ExpiresAt time.Time
+}
+
+// Backing persists entries beyond the process, a storage.Repository of
+// Entry values satisfies it.
+type Backing[V any] interface {
+	Get(key string) (Entry[V], bool, error)
+	Put(key string, entry Entry[V]) error
+	Delete(key string) error
+}
+
+type element[V any] struct {
+	key   string
+	entry Entry[V]
+}
+
+// Cache keeps up to capacity values, evicting the least recently used one
+// when full. Expired values are never returned.
+type Cache[V any] struct {
+	mu       sync.Mutex
+	capacity int
+	order    *list.List
+	items    map[string]*list.Element
+	backing  Backing[V]
+}
+
+// New returns an empty cache holding at most capacity values.
+func New[V any](capacity int) *Cache[V] {
+	return &Cache[V]{
+		capacity: capacity,
+		order:    list.New(),
+		items:    map[string]*list.Element{},
+	}
+}
+
+// SetBacking also writes values to backing, so they survive a restart.
+// Values missing from memory are looked up there.
+func (c *Cache[V]) SetBacking(backing Backing[V]) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.backing = backing
+}
+
+// Get returns the fresh value of key.
+func (c *Cache[V]) Get(key string) (V, bool) {
+	now := time.Now()
+	c.mu.Lock()
+	if elem, ok := c.items[key]; ok {
+		entry := elem.Value.(*element[V]).entry
+		if now.Before(entry.ExpiresAt) {
+			c.order.MoveToFront(elem)
+			c.mu.Unlock()
+			return entry.Value, true
+		}
+		c.remove(elem)
+	}
+	backing := c.backing
+	c.mu.Unlock()
+
+	var zero V
+	if backing == nil {
+		return zero, false
+	}
+	entry, ok, err := backing.Get(key)
+	if err != nil {
+		slog.Warn("cannot read cache entry", "key", key, "err", err)
+		return zero, false
+	}
+	if !ok {
+		return zero, false
+	}
+	if !now.Before(entry.ExpiresAt) {
+		backing.Delete(key)
+		return zero, false
+	}
+
+	c.mu.Lock()
+	c.add(key, entry)
+	c.mu.Unlock()
+	return entry.Value, true
+}
+
+// Set stores value under key until ttl has passed.
+func (c *Cache[V]) Set(key string, value V, ttl time.Duration) {
+	entry := Entry[V]{Value: value, ExpiresAt: time.Now().Add(ttl)}
+	c.mu.Lock()
+	c.add(key, entry)
+	backing := c.backing
+	c.mu.Unlock()
+
+	if backing != nil {
+		if err := backing.Put(key, entry); err != nil {
+			slog.Warn("cannot write cache entry", "key", key, "err", err)
+		}
+	}
+}
+
+// Len returns the number of values held in memory.
+func (c *Cache[V]) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+func (c *Cache[V]) add(key string, entry Entry[V]) {
+	if c.capacity <= 0 {
+		return
+	}
+	if elem, ok := c.items[key]; ok {
+		elem.Value.(*element[V]).entry = entry
+		c.order.MoveToFront(elem)
+		return
+	}
+	c.items[key] = c.order.PushFront(&element[V]{key: key, entry: entry})
+	for c.order.Len() > c.capacity {
+		c.remove(c.order.Back())
+	}
+}
+
+func (c *Cache[V]) remove(elem *list.Element) {
+	c.order.Remove(elem)
+	delete(c.items, elem.Value.(*element[V]).key)
+}
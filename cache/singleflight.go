@@ -0,0 +1,58 @@
+package cache
+
+import (
+	"context"
+	"sync"
+)
+
+type call[V any] struct {
+	done  chan struct{}
+	value V
+	err   error
+}
+
+// Group coalesces concurrent calls with the same key into one.
+type Group[V any] struct {
+	// Joined, when set, is called with the key whenever a caller starts
+	// waiting for another caller's call.
+	Joined func(key string)
+
+	mu    sync.Mutex
+	calls map[string]*call[V]
+}
+
+// Do runs fn unless a call for key is already running, in which case it
+// waits for that call and returns its result, or ctx's error if ctx ends
+// first. shared reports whether the result came from another caller's
+// call.
+func (g *Group[V]) Do(ctx context.Context, key string, fn func() (V, error)) (value V, err error, shared bool) {
+	g.mu.Lock()
+	if g.calls == nil {
+		g.calls = map[string]*call[V]{}
+	}
+	if c, ok := g.calls[key]; ok {
+		g.mu.Unlock()
+		if g.Joined != nil {
+			g.Joined(key)
+		}
+		select {
+		case <-c.done:
+			return c.value, c.err, true
+		case <-ctx.Done():
+			var zero V
+			return zero, ctx.Err(), true
+		}
+	}
+	c := &call[V]{done: make(chan struct{})}
+	g.calls[key] = c
+	g.mu.Unlock()
+
+	defer func() {
+		g.mu.Lock()
+		delete(g.calls, key)
+		g.mu.Unlock()
+		close(c.done)
+	}()
+	c.value, c.err = fn()
+	return c.value, c.err, false
+}
@@ -0,0 +1,77 @@
+package citation
+
+import (
+	"fmt"
+	"strings"
+
+	"scholar-bot/apihandlers"
+)
+
+// Style renders a study as a reference string in one citation style.
+type Style struct {
+	Name   string
+	Format func(study apihandlers.StudyStruct) string
+}
+
+// Styles holds every citation style keyed by its command choice value.
+var Styles = map[string]Style{
+	"apa":       {Name: "APA", Format: APA},
+	"vancouver": {Name: "Vancouver", Format: Vancouver},
+	"mla":       {Name: "MLA", Format: MLA},
+	"chicago":   {Name: "Chicago", Format: Chicago},
+}
+
+// DefaultStyle is the style used when nobody asked for one: Vancouver for
+// PubMed records, as biomedical journals expect, and APA otherwise.
+func DefaultStyle(study apihandlers.StudyStruct) string {
+	if study.Source == "pubmed" {
+		return "vancouver"
+	}
+	return "apa"
+}
+
+// Format renders study in the style with the given key.
+func Format(study apihandlers.StudyStruct, styleName string) (string, error) {
+	style, ok := Styles[styleName]
+	if !ok {
+		return "", fmt.Errorf("unknown citation style %q", styleName)
+	}
+	return style.Format(study), nil
+}
+
+// sentence makes sure a title or other element ends with punctuation.
+func sentence(text string) string {
+	text = strings.TrimSpace(text)
+	if text == "" || strings.ContainsAny(text[len(text)-1:], ".?!") {
+		return text
+	}
+	return text + "."
+}
+
+func doiUrl(doi string) string {
+	if doi == "" {
+		return ""
+	}
+	return "https://doi.org/" + doi
+}
+
+// location returns the DOI link if there is one, or the study URL.
+func location(study apihandlers.StudyStruct) string {
+	if study.Doi != "" {
+		return doiUrl(study.Doi)
+	}
+	return study.Url
+}
+
+// joinList joins items as "a, b, and c" with the given conjunction.
+func joinList(items []string, separator string, conjunction string) string {
+	switch len(items) {
+	case 0:
+		return ""
+	case 1:
+		return items[0]
+	case 2:
+		return items[0] + " " + conjunction + " " + items[1]
+	}
+	return strings.Join(items[:len(items)-1], separator) + separator + conjunction + " " + items[len(items)-1]
+}
@@ -0,0 +1,121 @@
+package citation
+
+import (
+	"testing"
+
+	"scholar-bot/apihandlers"
+)
+
+var pubmedStudy = apihandlers.StudyStruct{
+	Title:  "CRISPR editing of sickle cell disease",
+	Source: "pubmed",
+	AuthorList: []apihandlers.Author{
+		{LastName: "Smith", ForeName: "John Adam", Initials: "JA"},
+		{LastName: "Doe", Initials: "B"},
+	},
+	Venue:         "The New England journal of medicine",
+	JournalAbbrev: "N Engl J Med",
+	Year:          "2021",
+	Month:         "Jan",
+	Volume:        "384",
+	Issue:         "3",
+	Pages:         "252-260",
+	Doi:           "10.1056/NEJMoa2031054",
+	Pmid:          "33283989",
+	Url:           "https://pubmed.ncbi.nlm.nih.gov/33283989/",
+}
+
+var scholarStudy = apihandlers.StudyStruct{
+	Title:      "Attention is all you need",
+	Source:     "scholar",
+	AuthorList: []apihandlers.Author{{LastName: "Vaswani", Initials: "A"}},
+	Venue:      "Advances in neural information processing systems",
+	Year:       "2017",
+	Url:        "https://example.com/paper",
+}
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		style string
+		study apihandlers.StudyStruct
+		want  string
+	}{
+		{
+			style: "apa",
+			study: pubmedStudy,
+			want:  "Smith, J. A., & Doe, B. (2021). CRISPR editing of sickle cell disease. The New England journal of medicine, 384(3), 252–260. https://doi.org/10.1056/NEJMoa2031054",
+		},
+		{
+			style: "vancouver",
+			study: pubmedStudy,
+			want:  "Smith JA, Doe B. CRISPR editing of sickle cell disease. N Engl J Med. 2021 Jan;384(3):252-260. doi:10.1056/NEJMoa2031054. PMID: 33283989.",
+		},
+		{
+			style: "mla",
+			study: pubmedStudy,
+			want:  `Smith, John Adam, and B. Doe. "CRISPR editing of sickle cell disease." The New England journal of medicine, vol. 384, no. 3, Jan 2021, pp. 252-260. https://doi.org/10.1056/NEJMoa2031054.`,
+		},
+		{
+			style: "chicago",
+			study: pubmedStudy,
+			want:  `Smith, John Adam and B. Doe. "CRISPR editing of sickle cell disease." The New England journal of medicine 384, no. 3 (Jan 2021): 252–260. https://doi.org/10.1056/NEJMoa2031054.`,
+		},
+		{
+			style: "apa",
+			study: scholarStudy,
+			want:  "Vaswani, A. (2017). Attention is all you need. Advances in neural information processing systems. https://example.com/paper",
+		},
+		{
+			style: "vancouver",
+			study: scholarStudy,
+			want:  "Vaswani A. Attention is all you need. Advances in neural information processing systems. 2017. Available from: https://example.com/paper",
+		},
+		{
+			style: "mla",
+			study: scholarStudy,
+			want:  `Vaswani, A. "Attention is all you need." Advances in neural information processing systems, 2017. https://example.com/paper.`,
+		},
+		{
+			style: "chicago",
+			study: scholarStudy,
+			want:  `Vaswani, A. "Attention is all you need." Advances in neural information processing systems (2017). https://example.com/paper.`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.style+"/"+test.study.Source, func(t *testing.T) {
+			got, err := Format(test.study, test.style)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != test.want {
+				t.Errorf("got  %s\nwant %s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestFormatUnknownStyle(t *testing.T) {
+	if _, err := Format(pubmedStudy, "harvard"); err == nil {
+		t.Error("expected an error for an unknown style")
+	}
+}
+
+func TestVancouverEtAl(t *testing.T) {
+	study := apihandlers.StudyStruct{Title: "Many authors"}
+	for _, name := range []string{"A", "B", "C", "D", "E", "F", "G"} {
+		study.AuthorList = append(study.AuthorList, apihandlers.Author{LastName: name, Initials: "X"})
+	}
+	want := "A X, B X, C X, D X, E X, F X, et al. Many authors."
+	if got := Vancouver(study); got != want {
+		t.Errorf("got  %s\nwant %s", got, want)
+	}
+}
+
+func TestDefaultStyle(t *testing.T) {
+	if got := DefaultStyle(pubmedStudy); got != "vancouver" {
+		t.Errorf("DefaultStyle of a PubMed study = %q, want vancouver", got)
+	}
+	if got := DefaultStyle(scholarStudy); got != "apa" {
+		t.Errorf("DefaultStyle of a Scholar study = %q, want apa", got)
+	}
+}
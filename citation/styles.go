@@ -0,0 +1,223 @@
+package citation
+
+import (
+	"strings"
+
+	"scholar-bot/apihandlers"
+)
+
+// Vancouver follows the ICMJE/NLM style: up to six authors, then "et al".
+func Vancouver(study apihandlers.StudyStruct) string {
+	var authors []string
+	for i, author := range study.AuthorList {
+		if i == 6 {
+			authors = append(authors, "et al")
+			break
+		}
+		authors = append(authors, strings.TrimSpace(author.LastName+" "+strings.Join(author.InitialLetters(), "")))
+	}
+
+	var parts []string
+	if len(authors) != 0 {
+		parts = append(parts, strings.Join(authors, ", ")+".")
+	}
+	parts = append(parts, sentence(study.Title))
+
+	journal := study.JournalAbbrev
+	if journal == "" {
+		journal = study.Venue
+	}
+	if journal != "" {
+		parts = append(parts, sentence(journal))
+	}
+
+	var published string
+	published += study.Year
+	if study.Month != "" && study.Year != "" {
+		published += " " + study.Month
+	}
+	if study.Volume != "" {
+		published += ";" + study.Volume
+		if study.Issue != "" {
+			published += "(" + study.Issue + ")"
+		}
+	}
+	if study.Pages != "" {
+		published += ":" + study.Pages
+	}
+	if published != "" {
+		parts = append(parts, published+".")
+	}
+
+	if study.Doi != "" {
+		parts = append(parts, "doi:"+study.Doi+".")
+	}
+	if study.Pmid != "" {
+		parts = append(parts, "PMID: "+study.Pmid+".")
+	} else if study.Doi == "" && study.Url != "" {
+		parts = append(parts, "Available from: "+study.Url)
+	}
+
+	return strings.Join(parts, " ")
+}
+
+// APA follows the 7th edition: up to 20 authors with initials.
+func APA(study apihandlers.StudyStruct) string {
+	var authors []string
+	for _, author := range study.AuthorList {
+		name := author.LastName
+		if letters := author.InitialLetters(); len(letters) != 0 {
+			name += ", " + strings.Join(letters, ". ") + "."
+		}
+		authors = append(authors, name)
+	}
+	if len(authors) > 20 {
+		authors = append(authors[:19], "... "+authors[len(authors)-1])
+	}
+
+	var parts []string
+	if len(authors) == 1 {
+		parts = append(parts, authors[0])
+	} else if len(authors) > 1 {
+		parts = append(parts, strings.Join(authors[:len(authors)-1], ", ")+", & "+authors[len(authors)-1])
+	}
+
+	if study.Year != "" {
+		parts = append(parts, "("+study.Year+").")
+	} else {
+		parts = append(parts, "(n.d.).")
+	}
+	parts = append(parts, sentence(study.Title))
+
+	if study.Venue != "" {
+		source := study.Venue
+		if study.Volume != "" {
+			source += ", " + study.Volume
+			if study.Issue != "" {
+				source += "(" + study.Issue + ")"
+			}
+		}
+		if start, end := study.PageRange(); end != "" {
+			source += ", " + start + "–" + end
+		} else if start != "" {
+			source += ", " + start
+		}
+		parts = append(parts, source+".")
+	}
+
+	if link := location(study); link != "" {
+		parts = append(parts, link)
+	}
+
+	return strings.Join(parts, " ")
+}
+
+// MLA follows the 9th edition: one author, two authors, or first author et al.
+func MLA(study apihandlers.StudyStruct) string {
+	var parts []string
+	switch len(study.AuthorList) {
+	case 0:
+	case 1:
+		parts = append(parts, sentence(invertedName(study.AuthorList[0])))
+	case 2:
+		parts = append(parts, sentence(
+			invertedName(study.AuthorList[0])+", and "+directName(study.AuthorList[1]),
+		))
+	default:
+		parts = append(parts, invertedName(study.AuthorList[0])+", et al.")
+	}
+	parts = append(parts, "\""+sentence(study.Title)+"\"")
+
+	var container []string
+	if study.Venue != "" {
+		container = append(container, study.Venue)
+	}
+	if study.Volume != "" {
+		container = append(container, "vol. "+study.Volume)
+	}
+	if study.Issue != "" {
+		container = append(container, "no. "+study.Issue)
+	}
+	if study.Year != "" {
+		container = append(container, strings.TrimSpace(study.Month+" "+study.Year))
+	}
+	if start, end := study.PageRange(); end != "" {
+		container = append(container, "pp. "+start+"-"+end)
+	} else if start != "" {
+		container = append(container, "p. "+start)
+	}
+	if len(container) != 0 {
+		parts = append(parts, strings.Join(container, ", ")+".")
+	}
+
+	if link := location(study); link != "" {
+		parts = append(parts, link+".")
+	}
+
+	return strings.Join(parts, " ")
+}
+
+// Chicago follows the notes and bibliography style's bibliography entry.
+func Chicago(study apihandlers.StudyStruct) string {
+	var authors []string
+	for i, author := range study.AuthorList {
+		if i == 0 {
+			authors = append(authors, invertedName(author))
+		} else {
+			authors = append(authors, directName(author))
+		}
+	}
+	// More than ten authors are cut down to the first seven and et al.
+	if len(authors) > 10 {
+		authors = append(authors[:7], "et al")
+	}
+
+	var parts []string
+	if len(authors) != 0 {
+		if authors[len(authors)-1] == "et al" {
+			parts = append(parts, strings.Join(authors, ", ")+".")
+		} else {
+			parts = append(parts, sentence(joinList(authors, ", ", "and")))
+		}
+	}
+	parts = append(parts, "\""+sentence(study.Title)+"\"")
+
+	var source string
+	source += study.Venue
+	if study.Volume != "" {
+		source += " " + study.Volume
+	}
+	if study.Issue != "" {
+		source += ", no. " + study.Issue
+	}
+	if study.Year != "" {
+		source += " (" + strings.TrimSpace(study.Month+" "+study.Year) + ")"
+	}
+	if start, end := study.PageRange(); end != "" {
+		source += ": " + start + "–" + end
+	} else if start != "" {
+		source += ": " + start
+	}
+	if source = strings.TrimSpace(source); source != "" {
+		parts = append(parts, source+".")
+	}
+
+	if link := location(study); link != "" {
+		parts = append(parts, link+".")
+	}
+
+	return strings.Join(parts, " ")
+}
+
+// invertedName returns "Last, Given" for the first author in MLA and Chicago.
+func invertedName(author apihandlers.Author) string {
+	if given := author.GivenName(); given != "" {
+		return author.LastName + ", " + given
+	}
+	return author.LastName
+}
+
+// directName returns "Given Last" for the following authors.
+func directName(author apihandlers.Author) string {
+	return strings.TrimSpace(author.GivenName() + " " + author.LastName)
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"scholar-bot/apihandlers"
+	"scholar-bot/citation"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// gsCiteFormats is the order the citation format buttons are shown in.
+var gsCiteFormats = []string{
+	apihandlers.GsCiteBibTeX,
+	apihandlers.GsCiteEndNote,
+	apihandlers.GsCiteRefMan,
+}
+
+// GsCiteComponentHandler answers the "Cite" button with the citation of the
+// Scholar result attached as a file, offering the other formats as buttons.
+func GsCiteComponentHandler(ctx context.Context, botSession *discordgo.Session, botInteraction *discordgo.InteractionCreate) {
+	// Custom ID is "gs_cite:<format>:<data-cid>"
+	args := strings.SplitN(botInteraction.MessageComponentData().CustomID, ":", 3)
+	if len(args) != 3 {
+		RespondHelper(ctx, botSession, botInteraction,
+			&discordgo.InteractionResponse{
+				Type: discordgo.InteractionResponseChannelMessageWithSource,
+				Data: &discordgo.InteractionResponseData{
+					Content: "An error happened when retrieving the citation",
+					Flags:   1 << 6,
+				},
+			})
+		return
+	}
+	format, citeId := args[1], args[2]
+
+	citation, err := apihandlers.QueryGsCitation(ctx, citeId, format)
+	if err != nil {
+		RespondHelper(ctx, botSession, botInteraction,
+			&discordgo.InteractionResponse{
+				Type: discordgo.InteractionResponseChannelMessageWithSource,
+				Data: &discordgo.InteractionResponseData{
+					Content: ErrorMessageHelper(err, "Google Scholar"),
+					Flags:   1 << 6,
+				},
+			})
+		return
+	}
+
+	var otherFormats []discordgo.MessageComponent
+	for _, otherFormat := range gsCiteFormats {
+		if otherFormat == format {
+			continue
+		}
+		otherFormats = append(otherFormats, discordgo.Button{
+			Label:    otherFormat,
+			Style:    discordgo.SecondaryButton,
+			CustomID: fmt.Sprintf("gs_cite:%s:%s", otherFormat, citeId),
+		})
+	}
+
+	RespondHelper(ctx, botSession, botInteraction,
+		&discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{
+				Content: fmt.Sprintf("%s citation", format),
+				Files: []*discordgo.File{
+					{
+						Name:        fmt.Sprintf("citation.%s", apihandlers.GsCiteFileExtensions[format]),
+						ContentType: "text/plain",
+						Reader:      strings.NewReader(citation),
+					},
+				},
+				Components: []discordgo.MessageComponent{
+					discordgo.ActionsRow{Components: otherFormats},
+				},
+				Flags: 1 << 6,
+			},
+		})
+}
+
+func CitationStyleChoicesHelper() []*discordgo.ApplicationCommandOptionChoice {
+	var choices []*discordgo.ApplicationCommandOptionChoice
+	for value, style := range citation.Styles {
+		choices = append(choices, &discordgo.ApplicationCommandOptionChoice{
+			Name: style.Name, Value: value,
+		})
+	}
+	sort.Slice(choices, func(i, j int) bool { return choices[i].Name < choices[j].Name })
+	return choices
+}
+
+// CitationStyleHelper picks the requested style, then the guild's default,
+// then the default for the study's source.
+func CitationStyleHelper(
+	optionMap map[string]*discordgo.ApplicationCommandInteractionDataOption,
+	settings GuildSettings,
+	study apihandlers.StudyStruct,
+) string {
+	if style, ok := optionMap["style"]; ok {
+		return style.StringValue()
+	}
+	if settings.CitationStyle != "" {
+		return settings.CitationStyle
+	}
+	return citation.DefaultStyle(study)
+}
+
+func CiteCommandHandler(ctx context.Context, botSession *discordgo.Session, botInteraction *discordgo.InteractionCreate) {
+	options := botInteraction.ApplicationCommandData().Options
+	optionMap := make(
+		map[string]*discordgo.ApplicationCommandInteractionDataOption,
+		len(options),
+	)
+	for _, opt := range options {
+		optionMap[opt.Name] = opt
+	}
+
+	query, ok := optionMap["google"]
+	if !ok {
+		RespondHelper(ctx, botSession, botInteraction,
+			&discordgo.InteractionResponse{
+				Type: discordgo.InteractionResponseChannelMessageWithSource,
+				Data: &discordgo.InteractionResponseData{
+					Content: "An error happened when retrieving the query",
+					Flags:   1 << 6,
+				},
+			})
+		return
+	}
+
+	settings := GuildSettingsHelper(botInteraction.GuildID)
+	sourceName, source := SourceOptionHelper(optionMap, settings)
+
+	study, err := source.QueryFirst(ctx, query.StringValue(), YearInputHelper(optionMap, settings))
+	var reference string
+	if err == nil {
+		styleName := CitationStyleHelper(optionMap, settings, *study)
+		reference, err = citation.Format(*study, styleName)
+	}
+	if err != nil {
+		RespondHelper(ctx, botSession, botInteraction,
+			&discordgo.InteractionResponse{
+				Type: discordgo.InteractionResponseChannelMessageWithSource,
+				Data: &discordgo.InteractionResponseData{
+					Content: ErrorMessageHelper(err, source.Name()),
+					Flags:   1 << 6,
+				},
+			})
+		return
+	}
+
+	RespondHelper(ctx, botSession, botInteraction,
+		&discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{
+				Content: FallbackNoticeHelper(sourceName, study.Source) + "```\n" + reference + "\n```",
+				Flags:   ResultFlagsHelper(settings),
+			},
+		})
+}
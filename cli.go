@@ -0,0 +1,177 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"scholar-bot/apihandlers"
+	"scholar-bot/config"
+	"scholar-bot/exporters"
+)
+
+// SearchCLIHelper runs "scholar-bot search [flags] query" against the
+// sources directly, without Discord, and returns the exit code.
+func SearchCLIHelper(args []string, stdout io.Writer, stderr io.Writer) int {
+	flags := flag.NewFlagSet("scholar-bot search", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	sourceName := flags.String("source", "pubmed", "source to search: pubmed or scholar")
+	minYear := flags.Int("min-year", defaultMinYear, "only show studies published since this year")
+	format := flags.String("format", "text", "output format: text, json, "+strings.Join(ExportFormatNamesHelper(), ", "))
+	first := flags.Bool("first", false, "only show the first study found")
+	configPath := flags.String("config", os.Getenv("scholar_bot_config"), "configuration file (default "+config.DefaultPath+" if it exists)")
+	verbose := flags.Bool("v", false, "log the requests to the sources")
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: scholar-bot search [flags] query")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return exitConfig
+	}
+	query := strings.Join(flags.Args(), " ")
+	if query == "" {
+		flags.Usage()
+		return exitConfig
+	}
+	if _, ok := exporters.Formats[*format]; !ok && *format != "text" && *format != "json" {
+		fmt.Fprintf(stderr, "unknown format %q\n", *format)
+		return exitConfig
+	}
+
+	var configArgs []string
+	if *configPath != "" {
+		configArgs = []string{"-config", *configPath}
+	}
+	cfg, _, err := config.Load(configArgs)
+	if err == nil {
+		err = cfg.Validate()
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "invalid configuration:\n%v\n", err)
+		return exitConfig
+	}
+
+	logConfig := cfg.Log
+	if !*verbose {
+		logConfig.Level = "warn"
+	}
+	logger, err := LoggerHelper(stderr, logConfig)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitConfig
+	}
+	slog.SetDefault(logger)
+	if err := ApplyConfigHelper(cfg); err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitConfig
+	}
+
+	source, ok := apihandlers.Sources[*sourceName]
+	if !ok {
+		fmt.Fprintf(stderr, "unknown source %q\n", *sourceName)
+		return exitConfig
+	}
+	if *sourceName == "scholar" {
+		source = ScholarSourceHelper()
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+	var studies []apihandlers.StudyStruct
+	if *first {
+		var study *apihandlers.StudyStruct
+		study, err = source.QueryFirst(ctx, query, fmt.Sprint(*minYear))
+		if err == nil {
+			studies = []apihandlers.StudyStruct{*study}
+		}
+	} else {
+		var studySlice *[]apihandlers.StudyStruct
+		studySlice, err = source.QueryTopTen(ctx, query, fmt.Sprint(*minYear))
+		if err == nil {
+			studies = *studySlice
+		}
+	}
+	if err != nil {
+		slog.Debug("search failed", "err", err)
+		fmt.Fprintln(stderr, ErrorMessageHelper(err, source.Name()))
+		return exitFatal
+	}
+	if notice := FallbackNoticeHelper(*sourceName, studies[0].Source); notice != "" {
+		fmt.Fprint(stderr, notice)
+	}
+
+	output, err := SearchOutputHelper(studies, *format)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitFatal
+	}
+	fmt.Fprint(stdout, output)
+	return 0
+}
+
+// SearchOutputHelper renders studies as text, JSON or one of the export
+// formats.
+func SearchOutputHelper(studies []apihandlers.StudyStruct, format string) (string, error) {
+	switch format {
+	case "text":
+		return StudyPlainTextHelper(studies), nil
+	case "json":
+		output, err := json.MarshalIndent(studies, "", "  ")
+		if err != nil {
+			return "", err
+		}
+		return string(output) + "\n", nil
+	}
+	output, err := exporters.Formats[format].Export(studies)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(output, "\n") + "\n", nil
+}
+
+// StudyPlainTextHelper lists studies for a terminal.
+func StudyPlainTextHelper(studies []apihandlers.StudyStruct) string {
+	var builder strings.Builder
+	for i, study := range studies {
+		if i != 0 {
+			builder.WriteString("\n")
+		}
+		fmt.Fprintf(&builder, "%d. %s\n", i+1, study.Title)
+		if study.Authors != "" {
+			fmt.Fprintf(&builder, "   %s\n", study.Authors)
+		}
+
+		var details []string
+		if study.Venue != "" {
+			details = append(details, study.Venue)
+		}
+		if study.Year != "" {
+			details = append(details, study.Year)
+		}
+		if study.CitedByUrl != "" {
+			details = append(details, fmt.Sprintf("cited by %d", study.CitedBy))
+		}
+		if study.Doi != "" {
+			details = append(details, "doi:"+study.Doi)
+		}
+		if len(details) != 0 {
+			fmt.Fprintf(&builder, "   %s\n", strings.Join(details, ", "))
+		}
+		fmt.Fprintf(&builder, "   %s\n", study.Url)
+		if study.PdfUrl != "" {
+			fmt.Fprintf(&builder, "   PDF: %s\n", study.PdfUrl)
+		}
+	}
+	return builder.String()
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"reflect"
+	"sync"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+var (
+	syncMu sync.Mutex
+	// syncedCommands are the definitions last synced to Discord.
+	syncedCommands []*discordgo.ApplicationCommand
+)
+
+// SyncCommandsHelper makes the commands registered on Discord match
+// commands, overwriting them in one request only when they differ.
+func SyncCommandsHelper(botSession *discordgo.Session, guildID string, commands []*discordgo.ApplicationCommand) error {
+	syncMu.Lock()
+	defer syncMu.Unlock()
+
+	appID := botSession.State.User.ID
+	existing, err := botSession.ApplicationCommands(appID, guildID)
+	if err != nil {
+		return fmt.Errorf("listing commands: %w", err)
+	}
+
+	if CommandsEqualHelper(existing, commands) {
+		slog.Info("commands already up to date", "commands", len(commands), "guild", guildID)
+		syncedCommands = commands
+		return nil
+	}
+
+	slog.Info("syncing commands", "commands", len(commands), "guild", guildID)
+	_, err = botSession.ApplicationCommandBulkOverwrite(appID, guildID, commands)
+	if err != nil {
+		return fmt.Errorf("overwriting commands: %w", err)
+	}
+	syncedCommands = commands
+	return nil
+}
+
+// CommandsChangedHelper reports whether commands differ from the
+// definitions last synced to Discord.
+func CommandsChangedHelper(commands []*discordgo.ApplicationCommand) bool {
+	syncMu.Lock()
+	defer syncMu.Unlock()
+	return !CommandsEqualHelper(syncedCommands, commands)
+}
+
+// RemoveGlobalCommandsHelper unregisters the global commands left by an
+// earlier run once the bot registers its commands on a single guild, so
+// they don't show twice there.
+func RemoveGlobalCommandsHelper(botSession *discordgo.Session, guildID string) error {
+	if guildID == "" {
+		return nil
+	}
+	existing, err := botSession.ApplicationCommands(botSession.State.User.ID, "")
+	if err != nil {
+		return fmt.Errorf("listing global commands: %w", err)
+	}
+	if len(existing) == 0 {
+		return nil
+	}
+	slog.Info("removing global commands", "commands", len(existing))
+	return RemoveCommandsHelper(botSession, "")
+}
+
+// RemoveCommandsHelper unregisters all of the bot's commands.
+func RemoveCommandsHelper(botSession *discordgo.Session, guildID string) error {
+	_, err := botSession.ApplicationCommandBulkOverwrite(
+		botSession.State.User.ID,
+		guildID,
+		[]*discordgo.ApplicationCommand{},
+	)
+	return err
+}
+
+// CommandsEqualHelper reports whether the commands registered on Discord
+// have the same definitions as wanted, ignoring order and server-set fields.
+func CommandsEqualHelper(registered, wanted []*discordgo.ApplicationCommand) bool {
+	if len(registered) != len(wanted) {
+		return false
+	}
+	byName := make(map[string]*discordgo.ApplicationCommand, len(registered))
+	for _, cmd := range registered {
+		byName[cmd.Name] = cmd
+	}
+	for _, want := range wanted {
+		have, ok := byName[want.Name]
+		if !ok || !commandEqual(have, want) {
+			return false
+		}
+	}
+	return true
+}
+
+func commandEqual(have, want *discordgo.ApplicationCommand) bool {
+	haveType, wantType := have.Type, want.Type
+	if haveType == 0 {
+		haveType = discordgo.ChatApplicationCommand
+	}
+	if wantType == 0 {
+		wantType = discordgo.ChatApplicationCommand
+	}
+	if haveType != wantType || have.Description != want.Description {
+		return false
+	}
+
+	var havePermissions, wantPermissions int64
+	if have.DefaultMemberPermissions != nil {
+		havePermissions = *have.DefaultMemberPermissions
+	}
+	if want.DefaultMemberPermissions != nil {
+		wantPermissions = *want.DefaultMemberPermissions
+	}
+	if havePermissions != wantPermissions {
+		return false
+	}
+
+	return optionsEqual(have.Options, want.Options)
+}
+
+func optionsEqual(have, want []*discordgo.ApplicationCommandOption) bool {
+	if len(have) != len(want) {
+		return false
+	}
+	for i := range want {
+		h, w := have[i], want[i]
+		if h.Type != w.Type ||
+			h.Name != w.Name ||
+			h.Description != w.Description ||
+			h.Required != w.Required ||
+			h.Autocomplete != w.Autocomplete ||
+			h.MaxValue != w.MaxValue ||
+			h.MaxLength != w.MaxLength {
+			return false
+		}
+		if !reflect.DeepEqual(h.MinValue, w.MinValue) || !reflect.DeepEqual(h.MinLength, w.MinLength) {
+			return false
+		}
+		if len(h.ChannelTypes) != len(w.ChannelTypes) {
+			return false
+		}
+		for j := range w.ChannelTypes {
+			if h.ChannelTypes[j] != w.ChannelTypes[j] {
+				return false
+			}
+		}
+		if !choicesEqual(h.Choices, w.Choices) || !optionsEqual(h.Options, w.Options) {
+			return false
+		}
+	}
+	return true
+}
+
+func choicesEqual(have, want []*discordgo.ApplicationCommandOptionChoice) bool {
+	if len(have) != len(want) {
+		return false
+	}
+	for i := range want {
+		// Values come back from Discord as JSON numbers or strings
+		if have[i].Name != want[i].Name ||
+			fmt.Sprint(have[i].Value) != fmt.Sprint(want[i].Value) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,501 @@
+// Package config loads the bot's settings from defaults, a TOML file, the
+// environment and the command line, each overriding the previous one.
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"scholar-bot/ratelimit"
+)
+
+// DefaultPath is read when neither -config nor scholar_bot_config name a
+// configuration file, if it exists.
+const DefaultPath = "scholar-bot.toml"
+
+// Config holds every setting of the bot. Each leaf field has a key in the
+// file, an environment variable (with * standing for the enclosing table)
+// and, unless it is a secret, a command-line flag. Settings tagged restart
+// are only read at startup.
+type Config struct {
+	Discord    DiscordConfig    `toml:"discord"`
+	NCBI       NCBIConfig       `toml:"ncbi"`
+	Sources    SourcesConfig    `toml:"sources"`
+	RateLimits RateLimitsConfig `toml:"rate_limits"`
+	Cache      CacheConfig      `toml:"cache"`
+	Storage    StorageConfig    `toml:"storage"`
+	Log        LogConfig        `toml:"log"`
+	HTTP       HTTPConfig       `toml:"http"`
+}
+
+type DiscordConfig struct {
+	Token          string `toml:"token" env:"scholar_bot" secret:"true" restart:"true" help:"Discord bot token"`
+	Guild          string `toml:"guild" env:"scholar_bot_guild" flag:"guild" restart:"true" help:"register commands on this guild only (default: global)"`
+	RemoveCommands bool   `toml:"remove_commands" env:"scholar_bot_remove_commands" flag:"remove-commands" help:"unregister commands on shutdown"`
+	// ShutdownTimeout bounds how long shutdown waits for interactions and
+	// alerts in progress before cancelling them.
+	ShutdownTimeout time.Duration `toml:"shutdown_timeout" env:"scholar_bot_shutdown_timeout" help:"how long shutdown waits for work in progress"`
+}
+
+// NCBIConfig identifies the bot to the NCBI E-utilities.
+type NCBIConfig struct {
+	APIKey string `toml:"api_key" env:"scholar_bot_ncbi_api_key" secret:"true" help:"NCBI API key"`
+	Email  string `toml:"email" env:"scholar_bot_ncbi_email" help:"contact email sent to NCBI"`
+	Tool   string `toml:"tool" env:"scholar_bot_ncbi_tool" help:"tool name sent to NCBI"`
+}
+
+type SourcesConfig struct {
+	Fallback        string        `toml:"fallback" env:"scholar_bot_fallback" help:"source queried when Google Scholar is unavailable"`
+	Timeout         time.Duration `toml:"timeout" env:"scholar_bot_request_timeout" help:"timeout of each request to a source"`
+	BreakerFailures int           `toml:"breaker_failures" env:"scholar_bot_breaker_failures" help:"consecutive failures before a source is paused"`
+	BreakerOpenFor  time.Duration `toml:"breaker_open_for" env:"scholar_bot_breaker_open_for" help:"how long a failing source is paused"`
+	Scholar         SourceConfig  `toml:"scholar"`
+	PubMed          SourceConfig  `toml:"pubmed"`
+}
+
+// SourceConfig holds the settings of a single source.
+type SourceConfig struct {
+	Enabled     bool          `toml:"enabled" env:"scholar_bot_enabled_*" help:"query this source"`
+	Concurrency int           `toml:"concurrency" env:"scholar_bot_concurrency_*" help:"requests to this source running at once"`
+	CacheTTL    time.Duration `toml:"cache_ttl" env:"scholar_bot_cache_ttl_*" help:"how long results of this source are cached"`
+}
+
+// ByName returns the settings of each source, keyed by source name.
+func (c SourcesConfig) ByName() map[string]SourceConfig {
+	return map[string]SourceConfig{
+		"scholar": c.Scholar,
+		"pubmed":  c.PubMed,
+	}
+}
+
+// RateLimitsConfig holds interaction quotas such as "5/1m".
+type RateLimitsConfig struct {
+	User    string `toml:"user" env:"scholar_bot_limit_user" help:"interactions allowed per user"`
+	Channel string `toml:"channel" env:"scholar_bot_limit_channel" help:"interactions allowed per channel"`
+	Guild   string `toml:"guild" env:"scholar_bot_limit_guild" help:"interactions allowed per guild"`
+}
+
+type CacheConfig struct {
+	Size    int  `toml:"size" env:"scholar_bot_cache_size" restart:"true" help:"search results kept in memory, 0 disables the cache"`
+	Persist bool `toml:"persist" env:"scholar_bot_cache_persist" restart:"true" help:"keep cached results across restarts"`
+}
+
+type StorageConfig struct {
+	Path string `toml:"path" env:"scholar_bot_storage" restart:"true" help:"database file, empty for in-memory storage"`
+}
+
+type LogConfig struct {
+	Format string `toml:"format" env:"scholar_bot_log_format" restart:"true" help:"log format, text or json"`
+	Level  string `toml:"level" env:"scholar_bot_log_level" help:"log level, debug, info, warn or error"`
+}
+
+type HTTPConfig struct {
+	FeedAddr    string `toml:"feed_addr" env:"scholar_bot_feed_addr" restart:"true" help:"address serving alert feeds, empty to disable"`
+	FeedURL     string `toml:"feed_url" env:"scholar_bot_feed_url" restart:"true" help:"public URL of the feed server"`
+	MetricsAddr string `toml:"metrics_addr" env:"scholar_bot_metrics_addr" restart:"true" help:"address serving metrics and health checks, empty to disable"`
+	APIAddr     string `toml:"api_addr" env:"scholar_bot_api_addr" restart:"true" help:"address serving the search API, empty to disable"`
+	// APIKeys is a comma-separated list of the keys accepted by the API.
+	APIKeys string `toml:"api_keys" env:"scholar_bot_api_keys" secret:"true" help:"comma-separated keys accepted by the search API"`
+}
+
+// APIKeyList returns the keys accepted by the API.
+func (c HTTPConfig) APIKeyList() []string {
+	var keys []string
+	for _, key := range strings.Split(c.APIKeys, ",") {
+		if key = strings.TrimSpace(key); key != "" {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// Default returns the settings used when nothing overrides them.
+func Default() *Config {
+	return &Config{
+		Discord: DiscordConfig{ShutdownTimeout: 10 * time.Second},
+		NCBI:    NCBIConfig{Tool: "scholar-bot"},
+		Sources: SourcesConfig{
+			Timeout:         20 * time.Second,
+			BreakerFailures: 5,
+			BreakerOpenFor:  time.Minute,
+			// Scholar gets a single request at a time and long-lived
+			// results, as every request to it risks a CAPTCHA
+			Scholar: SourceConfig{Enabled: true, Concurrency: 1, CacheTTL: 24 * time.Hour},
+			// NCBI allows three requests per second without an API key
+			PubMed: SourceConfig{Enabled: true, Concurrency: 3, CacheTTL: time.Hour},
+		},
+		RateLimits: RateLimitsConfig{User: "5/1m", Channel: "20/1m", Guild: "60/1m"},
+		Cache:      CacheConfig{Size: 1000},
+		Storage:    StorageConfig{Path: "scholar-bot.db"},
+		Log:        LogConfig{Format: "text", Level: "info"},
+	}
+}
+
+// Options are the command-line flags that aren't settings.
+type Options struct {
+	// Path is the configuration file that was read, if any.
+	Path        string
+	PrintConfig bool
+}
+
+// Load builds the configuration from the defaults, the configuration file,
+// the environment and args, in increasing priority. It doesn't validate it.
+func Load(args []string) (*Config, Options, error) {
+	var options Options
+	c := Default()
+	fields := c.fields()
+
+	flags := flag.NewFlagSet("scholar-bot", flag.ContinueOnError)
+	flags.StringVar(&options.Path, "config", os.Getenv("scholar_bot_config"), "configuration file (default "+DefaultPath+" if it exists)")
+	flags.BoolVar(&options.PrintConfig, "print-config", false, "print the configuration with secrets redacted and exit")
+	flagValues := map[string]string{}
+	for _, f := range fields {
+		if f.flag != "" {
+			flags.Var(flagValue{field: f, values: flagValues}, f.flag, fmt.Sprintf("%s (env %s)", f.help, f.env))
+		}
+	}
+	if err := flags.Parse(args); err != nil {
+		return nil, options, err
+	}
+	if flags.NArg() != 0 {
+		return nil, options, fmt.Errorf("unexpected argument %q", flags.Arg(0))
+	}
+
+	if options.Path == "" {
+		if _, err := os.Stat(DefaultPath); err == nil {
+			options.Path = DefaultPath
+		}
+	}
+	if options.Path != "" {
+		if err := c.readFile(options.Path, fields); err != nil {
+			return nil, options, err
+		}
+	}
+
+	var errs []error
+	for _, f := range fields {
+		value, ok := os.LookupEnv(f.env)
+		// An empty variable only clears strings, it's otherwise ignored
+		if !ok || (value == "" && f.value.Kind() != reflect.String) {
+			continue
+		}
+		if err := f.set(value); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", f.env, err))
+		}
+	}
+	for _, f := range fields {
+		if value, ok := flagValues[f.key]; ok {
+			if err := f.set(value); err != nil {
+				errs = append(errs, fmt.Errorf("-%s: %w", f.flag, err))
+			}
+		}
+	}
+	return c, options, errors.Join(errs...)
+}
+
+func (c *Config) readFile(path string, fields []field) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	values, err := parseTOML(file)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	var errs []error
+	for _, f := range fields {
+		value, ok := values[f.key]
+		if !ok {
+			continue
+		}
+		delete(values, f.key)
+		if err := f.setTOML(value); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %s: %w", path, f.key, err))
+		}
+	}
+	for key := range values {
+		errs = append(errs, fmt.Errorf("%s: unknown setting %s", path, key))
+	}
+	return errors.Join(errs...)
+}
+
+// Validate reports every invalid setting. The token isn't checked, as only
+// the bot needs it.
+func (c *Config) Validate() error {
+	var errs []error
+	invalid := func(key string, format string, args ...any) {
+		errs = append(errs, fmt.Errorf("%s: %s", key, fmt.Sprintf(format, args...)))
+	}
+
+	if c.Discord.ShutdownTimeout <= 0 {
+		invalid("discord.shutdown_timeout", "must be positive")
+	}
+
+	sources := c.Sources.ByName()
+	if fallback := c.Sources.Fallback; fallback != "" {
+		source, ok := sources[fallback]
+		switch {
+		case !ok:
+			invalid("sources.fallback", "unknown source %q", fallback)
+		case fallback == "scholar":
+			invalid("sources.fallback", "Google Scholar can't fall back to itself")
+		case !source.Enabled:
+			invalid("sources.fallback", "%s is disabled", fallback)
+		}
+	}
+	if c.Sources.Timeout <= 0 {
+		invalid("sources.timeout", "must be positive")
+	}
+	if c.Sources.BreakerFailures < 1 {
+		invalid("sources.breaker_failures", "must be at least 1")
+	}
+	if c.Sources.BreakerOpenFor <= 0 {
+		invalid("sources.breaker_open_for", "must be positive")
+	}
+	for name, source := range sources {
+		if source.Concurrency < 1 {
+			invalid("sources."+name+".concurrency", "must be at least 1")
+		}
+		if source.CacheTTL < 0 {
+			invalid("sources."+name+".cache_ttl", "can't be negative")
+		}
+	}
+
+	for key, quota := range map[string]string{
+		"rate_limits.user":    c.RateLimits.User,
+		"rate_limits.channel": c.RateLimits.Channel,
+		"rate_limits.guild":   c.RateLimits.Guild,
+	} {
+		if _, err := ratelimit.ParseQuota(quota); err != nil {
+			invalid(key, "%v", err)
+		}
+	}
+
+	if c.Cache.Size < 0 {
+		invalid("cache.size", "can't be negative")
+	}
+
+	switch strings.ToLower(c.Log.Format) {
+	case "text", "json":
+	default:
+		invalid("log.format", "must be text or json, got %q", c.Log.Format)
+	}
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(c.Log.Level)); err != nil {
+		invalid("log.level", "must be debug, info, warn or error, got %q", c.Log.Level)
+	}
+
+	if c.HTTP.FeedURL != "" && c.HTTP.FeedAddr == "" {
+		invalid("http.feed_url", "requires http.feed_addr")
+	}
+	if c.HTTP.APIAddr != "" && len(c.HTTP.APIKeyList()) == 0 {
+		invalid("http.api_keys", "required to serve the API")
+	}
+	return errors.Join(errs...)
+}
+
+// Write prints the configuration as TOML, each key followed by its
+// environment variable. Secrets are replaced by "<redacted>" unless
+// showSecrets is set.
+func (c *Config) Write(w io.Writer, showSecrets bool) error {
+	table := ""
+	for _, f := range c.fields() {
+		if t := f.table(); t != table {
+			if table != "" {
+				fmt.Fprintln(w)
+			}
+			fmt.Fprintf(w, "[%s]\n", t)
+			table = t
+		}
+		value := f.format()
+		if f.secret && !showSecrets && !f.value.IsZero() {
+			value = strconv.Quote("<redacted>")
+		}
+		_, err := fmt.Fprintf(w, "%s = %s # %s\n", f.name(), value, f.env)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Reload prepares next to replace c. The settings only read at startup keep
+// their value from c, those that differed are listed in restart. changed
+// lists the other settings that differ.
+func (c *Config) Reload(next *Config) (changed []string, restart []string) {
+	nextFields := next.fields()
+	for i, f := range c.fields() {
+		nextField := nextFields[i]
+		if nextField.value.Equal(f.value) {
+			continue
+		}
+		if f.restart {
+			restart = append(restart, f.key)
+			nextField.value.Set(f.value)
+		} else {
+			changed = append(changed, f.key)
+		}
+	}
+	return changed, restart
+}
+
+// field is a setting, pointing into a Config.
+type field struct {
+	key     string
+	env     string
+	flag    string
+	help    string
+	secret  bool
+	restart bool
+	value   reflect.Value
+}
+
+var durationType = reflect.TypeFor[time.Duration]()
+
+func (c *Config) fields() []field {
+	return walkFields(reflect.ValueOf(c).Elem(), "", "")
+}
+
+// walkFields lists the settings of a table before those of its sub-tables.
+func walkFields(v reflect.Value, prefix string, table string) []field {
+	var fields, nested []field
+	for i := range v.NumField() {
+		structField := v.Type().Field(i)
+		name := structField.Tag.Get("toml")
+		key := name
+		if prefix != "" {
+			key = prefix + "." + name
+		}
+		if structField.Type.Kind() == reflect.Struct {
+			nested = append(nested, walkFields(v.Field(i), key, name)...)
+			continue
+		}
+
+		f := field{
+			key:     key,
+			env:     strings.ReplaceAll(structField.Tag.Get("env"), "*", table),
+			flag:    structField.Tag.Get("flag"),
+			help:    structField.Tag.Get("help"),
+			secret:  structField.Tag.Get("secret") == "true",
+			restart: structField.Tag.Get("restart") == "true",
+			value:   v.Field(i),
+		}
+		if f.flag == "" && !f.secret {
+			f.flag = strings.NewReplacer(".", "-", "_", "-").Replace(key)
+		}
+		fields = append(fields, f)
+	}
+	return append(fields, nested...)
+}
+
+func (f field) table() string {
+	return f.key[:strings.LastIndex(f.key, ".")]
+}
+
+func (f field) name() string {
+	return f.key[strings.LastIndex(f.key, ".")+1:]
+}
+
+// set parses value from the environment or the command line.
+func (f field) set(value string) error {
+	switch {
+	case f.value.Type() == durationType:
+		duration, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("expected a duration such as 30s, got %q", value)
+		}
+		f.value.SetInt(int64(duration))
+	case f.value.Kind() == reflect.String:
+		f.value.SetString(value)
+	case f.value.Kind() == reflect.Int:
+		number, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("expected a number, got %q", value)
+		}
+		f.value.SetInt(int64(number))
+	case f.value.Kind() == reflect.Bool:
+		enabled, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("expected true or false, got %q", value)
+		}
+		f.value.SetBool(enabled)
+	}
+	return nil
+}
+
+// setTOML sets a value parsed from the configuration file.
+func (f field) setTOML(value any) error {
+	switch value := value.(type) {
+	case string:
+		if f.value.Kind() == reflect.String || f.value.Type() == durationType {
+			return f.set(value)
+		}
+	case int64:
+		if f.value.Kind() == reflect.Int && f.value.Type() != durationType {
+			f.value.SetInt(value)
+			return nil
+		}
+	case bool:
+		if f.value.Kind() == reflect.Bool {
+			f.value.SetBool(value)
+			return nil
+		}
+	}
+
+	switch {
+	case f.value.Type() == durationType:
+		return errors.New(`expected a duration such as "30s"`)
+	case f.value.Kind() == reflect.String:
+		return errors.New("expected a string")
+	case f.value.Kind() == reflect.Int:
+		return errors.New("expected a number")
+	}
+	return errors.New("expected true or false")
+}
+
+// format returns the value as TOML.
+func (f field) format() string {
+	switch {
+	case f.value.Type() == durationType:
+		return strconv.Quote(time.Duration(f.value.Int()).String())
+	case f.value.Kind() == reflect.String:
+		return strconv.Quote(f.value.String())
+	}
+	return fmt.Sprint(f.value.Interface())
+}
+
+// flagValue records a flag's value so that it is applied after the file and
+// the environment.
+type flagValue struct {
+	field  field
+	values map[string]string
+}
+
+func (v flagValue) String() string {
+	if !v.field.value.IsValid() || v.field.value.IsZero() {
+		return ""
+	}
+	if v.field.value.Kind() == reflect.String {
+		return v.field.value.String()
+	}
+	return strings.Trim(v.field.format(), `"`)
+}
+
+func (v flagValue) Set(value string) error {
+	v.values[v.field.key] = value
+	return nil
+}
+
+func (v flagValue) IsBoolFlag() bool {
+	return v.field.value.IsValid() && v.field.value.Kind() == reflect.Bool
+}
@@ -0,0 +1,221 @@
+package config
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseTOML(t *testing.T) {
+	tests := []struct {
+		name     string
+		document string
+		want     map[string]any
+		err      string
+	}{
+		{
+			name: "tables and values",
+			document: `
+# comment
+[discord]
+guild = "123" # trailing comment
+remove_commands = true
+
+[sources]
+timeout = '30s'
+breaker_failures = 1_000
+
+[sources.scholar]
+enabled = false
+`,
+			want: map[string]any{
+				"discord.guild":            "123",
+				"discord.remove_commands":  true,
+				"sources.timeout":          "30s",
+				"sources.breaker_failures": int64(1000),
+				"sources.scholar.enabled":  false,
+			},
+		},
+		{
+			name:     "dotted keys and inline tables",
+			document: "sources.pubmed.concurrency = 2\nlog = { level = \"debug\" }\n",
+			want: map[string]any{
+				"sources.pubmed.concurrency": int64(2),
+				"log.level":                  "debug",
+			},
+		},
+		{
+			name:     "escapes and multi-line strings",
+			document: "[ncbi]\ntool = \"a \\\"b\\\" # c\"\nemail = \"\"\"\nme@example.com\"\"\"\n",
+			want: map[string]any{
+				"ncbi.tool":  `a "b" # c`,
+				"ncbi.email": "me@example.com",
+			},
+		},
+		{
+			name:     "duplicate key",
+			document: "[log]\nlevel = \"info\"\nlevel = \"debug\"\n",
+			err:      "line 3",
+		},
+		{
+			name:     "missing value",
+			document: "[log]\n\nlevel =\n",
+			err:      "line 3",
+		},
+		{
+			name:     "unterminated string",
+			document: "[log]\nlevel = \"info\n",
+			err:      "line 2",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := parseTOML(strings.NewReader(test.document))
+			if test.err != "" {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Fatalf("error = %v, want one mentioning %q", err, test.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got  %v\nwant %v", got, test.want)
+			}
+		})
+	}
+}
+
+// loadFile loads the configuration from a file with the given contents.
+func loadFile(t *testing.T, document string, args ...string) (*Config, error) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "scholar-bot.toml")
+	if err := os.WriteFile(path, []byte(document), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	c, _, err := Load(append([]string{"-config", path}, args...))
+	return c, err
+}
+
+func TestLoadFile(t *testing.T) {
+	c, err := loadFile(t, `
+[discord]
+guild = "123"
+
+[sources]
+timeout = "30s"
+
+[sources.pubmed]
+concurrency = 5
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Discord.Guild != "123" || c.Sources.Timeout != 30*time.Second || c.Sources.PubMed.Concurrency != 5 {
+		t.Errorf("settings from the file weren't applied: %+v", c)
+	}
+	// Settings the file doesn't mention keep their defaults
+	if c.Sources.Scholar.Concurrency != 1 || c.Log.Level != "info" {
+		t.Errorf("defaults were lost: %+v", c)
+	}
+}
+
+func TestLoadFileErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		document string
+		want     []string
+	}{
+		{name: "unknown key", document: "[discord]\ntokn = \"x\"\n", want: []string{"unknown setting discord.tokn"}},
+		{name: "unknown table", document: "[sourcez]\ntimeout = \"1s\"\n", want: []string{"unknown setting sourcez.timeout"}},
+		{name: "wrong type", document: "[cache]\nsize = \"big\"\n", want: []string{"cache.size: expected a number"}},
+		{name: "duration as number", document: "[sources]\ntimeout = 30\n", want: []string{`expected a duration such as "30s"`}},
+		{name: "array", document: "[http]\napi_keys = [\"a\", \"b\"]\n", want: []string{"http.api_keys: expected a string"}},
+		{name: "float", document: "[cache]\nsize = 1.5\n", want: []string{"cache.size: expected a number"}},
+		{
+			name:     "every error is reported",
+			document: "[cache]\nsize = \"big\"\npersist = 1\n",
+			want:     []string{"cache.size: expected a number", "cache.persist: expected true or false"},
+		},
+		{name: "syntax error", document: "[log]\nlevel = info\n", want: []string{"line 2"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := loadFile(t, test.document)
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			for _, want := range test.want {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("error %q doesn't mention %q", err, want)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadPriority(t *testing.T) {
+	t.Setenv("scholar_bot_log_level", "warn")
+	t.Setenv("scholar_bot_cache_size", "20")
+	c, err := loadFile(t, "[log]\nlevel = \"debug\"\n[cache]\nsize = 10\n[storage]\npath = \"file.db\"\n", "-cache-size", "30")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The environment overrides the file, and flags override both
+	if c.Storage.Path != "file.db" || c.Log.Level != "warn" || c.Cache.Size != 30 {
+		t.Errorf("storage.path, log.level, cache.size = %q, %q, %d, want file.db, warn, 30",
+			c.Storage.Path, c.Log.Level, c.Cache.Size)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	if err := Default().Validate(); err != nil {
+		t.Errorf("the defaults are invalid: %v", err)
+	}
+
+	c := Default()
+	c.Sources.Fallback = "scholar"
+	c.Sources.PubMed.Concurrency = 0
+	c.HTTP.APIAddr = ":8080"
+	err := c.Validate()
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	for _, want := range []string{"sources.fallback", "sources.pubmed.concurrency", "http.api_keys"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q doesn't mention %s", err, want)
+		}
+	}
+}
+
+func TestWriteRoundTrip(t *testing.T) {
+	c := Default()
+	c.Discord.Token = "secret-token"
+	c.Sources.Scholar.Enabled = false
+	c.Sources.Timeout = 90 * time.Second
+
+	var redacted bytes.Buffer
+	if err := c.Write(&redacted, false); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(redacted.String(), "secret-token") {
+		t.Error("the token was written without showSecrets")
+	}
+
+	var written bytes.Buffer
+	if err := c.Write(&written, true); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := loadFile(t, written.String())
+	if err != nil {
+		t.Fatalf("cannot load the written configuration: %v\n%s", err, written.String())
+	}
+	if !reflect.DeepEqual(loaded, c) {
+		t.Errorf("loaded %+v\nwant   %+v", loaded, c)
+	}
+}
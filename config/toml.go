@@ -0,0 +1,33 @@
+package config
+
+import (
+	"io"
+
+	"github.com/BurntSushi/toml"
+)
+
+// parseTOML reads a TOML document and returns its values keyed by
+// "table.key", sub-tables included. Errors give the line at fault.
+func parseTOML(r io.Reader) (map[string]any, error) {
+	var document map[string]any
+	if _, err := toml.NewDecoder(r).Decode(&document); err != nil {
+		return nil, err
+	}
+	values := map[string]any{}
+	flattenTOML(values, "", document)
+	return values, nil
+}
+
+// flattenTOML adds the values of table to values, prefixing their keys.
+func flattenTOML(values map[string]any, prefix string, table map[string]any) {
+	for key, value := range table {
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+		if table, ok := value.(map[string]any); ok {
+			flattenTOML(values, key, table)
+			continue
+		}
+		values[key] = value
+	}
+}
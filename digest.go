@@ -0,0 +1,354 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+	_ "time/tzdata"
+	"unicode/utf8"
+
+	"scholar-bot/apihandlers"
+	"scholar-bot/storage"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+const (
+	// digestTopResults is how many papers each query shows in the digest
+	digestTopResults = 5
+	// digestEmbedsPerMessage is Discord's limit of embeds in one message
+	digestEmbedsPerMessage = 10
+	// messageEmbedsLimit is Discord's limit on the characters of all the
+	// embeds of a message
+	messageEmbedsLimit = 6000
+	// embedTitleLimit and embedDescriptionLimit are Discord's limits on
+	// the characters of an embed's title and description
+	embedTitleLimit       = 256
+	embedDescriptionLimit = 4096
+)
+
+var digestWeekdayChoices = []*discordgo.ApplicationCommandOptionChoice{
+	{Name: "Monday", Value: int(time.Monday)},
+	{Name: "Tuesday", Value: int(time.Tuesday)},
+	{Name: "Wednesday", Value: int(time.Wednesday)},
+	{Name: "Thursday", Value: int(time.Thursday)},
+	{Name: "Friday", Value: int(time.Friday)},
+	{Name: "Saturday", Value: int(time.Saturday)},
+	{Name: "Sunday", Value: int(time.Sunday)},
+}
+
+var errDigestDisabled = errors.New("digest is disabled")
+
+// LastDigestTimeHelper returns the most recent scheduled digest time at or
+// before now.
+func LastDigestTimeHelper(digest DigestSettings, now time.Time) (time.Time, error) {
+	location, err := time.LoadLocation(digest.Timezone)
+	if err != nil {
+		return time.Time{}, err
+	}
+	local := now.In(location)
+	scheduled := time.Date(
+		local.Year(), local.Month(), local.Day(),
+		digest.TimeOfDay/60, digest.TimeOfDay%60, 0, 0, location,
+	)
+	daysBack := (int(local.Weekday()) - int(digest.Weekday) + 7) % 7
+	scheduled = scheduled.AddDate(0, 0, -daysBack)
+	if scheduled.After(now) {
+		scheduled = scheduled.AddDate(0, 0, -7)
+	}
+	return scheduled, nil
+}
+
+// RunDueDigestsHelper sends the digest of every guild whose scheduled time
+// has passed since it was last sent.
+func RunDueDigestsHelper(botSession *discordgo.Session, now time.Time) {
+	records, err := guildSettings.List("")
+	if err != nil {
+		slog.Error("cannot load guild settings", "err", err)
+		return
+	}
+	for _, record := range records {
+		digest := record.Value.Digest
+		if digest == nil {
+			continue
+		}
+		scheduled, err := LastDigestTimeHelper(*digest, now)
+		if err != nil {
+			slog.Error("invalid digest timezone", "guild", record.Key, "err", err)
+			continue
+		}
+		if digest.LastSent.Before(scheduled) && !now.Before(digest.RetryAt) {
+			SendDigestHelper(botSession, record.Key, *digest, now)
+		}
+	}
+}
+
+// digestSection is the embed of one alert in the digest, with the number
+// of its pending papers the embed covers.
+type digestSection struct {
+	key   string
+	sent  int
+	embed *discordgo.MessageEmbed
+}
+
+// DigestSectionsHelper builds one section per alert with pending papers.
+func DigestSectionsHelper(records []storage.Record[Alert]) []digestSection {
+	var sections []digestSection
+	for _, record := range records {
+		alert := record.Value
+		if len(alert.Pending) == 0 {
+			continue
+		}
+		top := alert.Pending
+		if len(top) > digestTopResults {
+			top = top[:digestTopResults]
+		}
+		description := StudyListHelper(top)
+		if len(alert.Pending) > len(top) {
+			description += fmt.Sprintf("… and %d more", len(alert.Pending)-len(top))
+		}
+		sections = append(sections, digestSection{
+			key:  record.Key,
+			sent: len(alert.Pending),
+			embed: &discordgo.MessageEmbed{
+				Title:       truncateHelper(fmt.Sprintf("%s (%d new)", alert.Query, len(alert.Pending)), embedTitleLimit),
+				Description: truncateHelper(description, embedDescriptionLimit),
+				Footer: &discordgo.MessageEmbedFooter{
+					Text: fmt.Sprintf("%s alert %s", apihandlers.Sources[alert.Source].Name(), alert.ID),
+				},
+			},
+		})
+	}
+	return sections
+}
+
+// DigestChunksHelper splits the sections into messages within Discord's
+// limits on the number and the total length of embeds. There is always at
+// least one message, for the header.
+func DigestChunksHelper(sections []digestSection) [][]digestSection {
+	chunks := [][]digestSection{nil}
+	length := 0
+	for _, section := range sections {
+		last := len(chunks) - 1
+		sectionLength := embedLengthHelper(section.embed)
+		if len(chunks[last]) == digestEmbedsPerMessage || (len(chunks[last]) != 0 && length+sectionLength > messageEmbedsLimit) {
+			chunks = append(chunks, nil)
+			last++
+			length = 0
+		}
+		chunks[last] = append(chunks[last], section)
+		length += sectionLength
+	}
+	return chunks
+}
+
+// embedLengthHelper counts the characters of an embed Discord holds against
+// the total of a message.
+func embedLengthHelper(embed *discordgo.MessageEmbed) int {
+	length := utf8.RuneCountInString(embed.Title) + utf8.RuneCountInString(embed.Description)
+	if embed.Footer != nil {
+		length += utf8.RuneCountInString(embed.Footer.Text)
+	}
+	if embed.Author != nil {
+		length += utf8.RuneCountInString(embed.Author.Name)
+	}
+	for _, field := range embed.Fields {
+		length += utf8.RuneCountInString(field.Name) + utf8.RuneCountInString(field.Value)
+	}
+	return length
+}
+
+// truncateHelper cuts text to at most limit characters, ending with an
+// ellipsis when it was cut.
+func truncateHelper(text string, limit int) string {
+	if utf8.RuneCountInString(text) <= limit {
+		return text
+	}
+	return string([]rune(text)[:limit-1]) + "…"
+}
+
+// SendDigestHelper posts the guild's pending alert papers as a series of
+// embeds grouped by query, clearing them as they are posted. When posting
+// fails, the rest is tried again after alertRetryDelay.
+func SendDigestHelper(botSession *discordgo.Session, guildID string, digest DigestSettings, now time.Time) {
+	records, err := alerts.List(alertKey(guildID, ""))
+	if err != nil {
+		slog.Error("cannot load alerts", "guild", guildID, "err", err)
+		return
+	}
+
+	sections := DigestSectionsHelper(records)
+	total := 0
+	for _, record := range records {
+		total += len(record.Value.Pending)
+	}
+
+	header := fmt.Sprintf("📰 Weekly digest: %d new papers across %d searches", total, len(sections))
+	if len(sections) == 0 {
+		header = "📰 Weekly digest: no new papers this week"
+	}
+	for i, chunk := range DigestChunksHelper(sections) {
+		message := &discordgo.MessageSend{}
+		for _, section := range chunk {
+			message.Embeds = append(message.Embeds, section.embed)
+		}
+		if i == 0 {
+			message.Content = header
+		}
+		if _, err := botSession.ChannelMessageSendComplex(digest.ChannelID, message); err != nil {
+			slog.Error("cannot post digest, trying again later", "guild", guildID, "err", err, "retry_in", alertRetryDelay)
+			saveDigestHelper(guildID, func(digest *DigestSettings) {
+				digest.RetryAt = now.Add(alertRetryDelay)
+			})
+			return
+		}
+		// Clear what was posted so a retry only sends the rest
+		clearDigestSectionsHelper(chunk)
+	}
+
+	saveDigestHelper(guildID, func(digest *DigestSettings) {
+		digest.LastSent = now
+		digest.RetryAt = time.Time{}
+	})
+}
+
+// clearDigestSectionsHelper removes the papers of posted sections from the
+// pending papers of their alerts.
+func clearDigestSectionsHelper(sections []digestSection) {
+	for _, section := range sections {
+		err := alerts.Update(section.key, func(alert *Alert, exists bool) (bool, error) {
+			if !exists {
+				return false, errAlertNotFound
+			}
+			// Keep papers that arrived while the digest was being sent
+			alert.Pending = alert.Pending[min(section.sent, len(alert.Pending)):]
+			return false, nil
+		})
+		if err != nil && !errors.Is(err, errAlertNotFound) {
+			slog.Error("cannot clear digest of alert", "alert", section.key, "err", err)
+		}
+	}
+}
+
+// saveDigestHelper updates the guild's digest settings unless the digest
+// was disabled meanwhile.
+func saveDigestHelper(guildID string, fn func(digest *DigestSettings)) {
+	err := guildSettings.Update(guildID, func(settings *GuildSettings, exists bool) (bool, error) {
+		if settings.Digest == nil {
+			return false, errDigestDisabled
+		}
+		fn(settings.Digest)
+		return false, nil
+	})
+	if err != nil && !errors.Is(err, errDigestDisabled) {
+		slog.Error("cannot save digest", "guild", guildID, "err", err)
+	}
+}
+
+func DigestCommandHandler(ctx context.Context, botSession *discordgo.Session, botInteraction *discordgo.InteractionCreate) {
+	subcommand := botInteraction.ApplicationCommandData().Options[0]
+	optionMap := make(
+		map[string]*discordgo.ApplicationCommandInteractionDataOption,
+		len(subcommand.Options),
+	)
+	for _, opt := range subcommand.Options {
+		optionMap[opt.Name] = opt
+	}
+
+	if botInteraction.GuildID == "" {
+		EphemeralResponseHelper(ctx, botSession, botInteraction, "Digests can only be set up in servers")
+		return
+	}
+
+	switch subcommand.Name {
+	case "enable":
+		digest := DigestSettings{
+			ChannelID: botInteraction.ChannelID,
+			Weekday:   time.Monday,
+			TimeOfDay: 9 * 60,
+			Timezone:  "UTC",
+			// Don't send a digest right away for the slot that just passed
+			LastSent: time.Now(),
+		}
+		if channel, ok := optionMap["channel"]; ok {
+			digest.ChannelID = channel.ChannelValue(botSession).ID
+		}
+		if weekday, ok := optionMap["day"]; ok {
+			digest.Weekday = time.Weekday(weekday.IntValue())
+		}
+		if timeOfDay, ok := optionMap["time"]; ok {
+			parsed, err := time.Parse("15:04", strings.TrimSpace(timeOfDay.StringValue()))
+			if err != nil {
+				EphemeralResponseHelper(ctx, botSession, botInteraction, "The time must look like 09:00 or 17:30")
+				return
+			}
+			digest.TimeOfDay = parsed.Hour()*60 + parsed.Minute()
+		}
+		if timezone, ok := optionMap["timezone"]; ok {
+			digest.Timezone = strings.TrimSpace(timezone.StringValue())
+		}
+		if _, err := time.LoadLocation(digest.Timezone); err != nil {
+			EphemeralResponseHelper(
+				ctx,
+				botSession,
+				botInteraction,
+				fmt.Sprintf("Unknown timezone `%s`, use a name like Europe/Paris or America/New_York", digest.Timezone),
+			)
+			return
+		}
+
+		err := guildSettings.Update(botInteraction.GuildID, func(settings *GuildSettings, exists bool) (bool, error) {
+			settings.Digest = &digest
+			return false, nil
+		})
+		if err != nil {
+			apihandlers.Logger(ctx).Error("cannot save digest", "err", err)
+			EphemeralResponseHelper(ctx, botSession, botInteraction, "An error happened when saving the digest")
+			return
+		}
+		EphemeralResponseHelper(ctx, botSession, botInteraction, "Digest enabled: "+DigestDescriptionHelper(digest))
+
+	case "disable":
+		err := guildSettings.Update(botInteraction.GuildID, func(settings *GuildSettings, exists bool) (bool, error) {
+			settings.Digest = nil
+			return false, nil
+		})
+		if err != nil {
+			apihandlers.Logger(ctx).Error("cannot save digest", "err", err)
+			EphemeralResponseHelper(ctx, botSession, botInteraction, "An error happened when disabling the digest")
+			return
+		}
+		EphemeralResponseHelper(
+			ctx,
+			botSession,
+			botInteraction,
+			"Digest disabled, alerts will post new papers as they find them",
+		)
+
+	case "status":
+		settings, _, err := guildSettings.Get(botInteraction.GuildID)
+		if err != nil {
+			apihandlers.Logger(ctx).Error("cannot load guild settings", "err", err)
+		}
+		if settings.Digest == nil {
+			EphemeralResponseHelper(
+				ctx,
+				botSession,
+				botInteraction,
+				"The digest is disabled, alerts post new papers as they find them",
+			)
+			return
+		}
+		EphemeralResponseHelper(ctx, botSession, botInteraction, "Digest enabled: "+DigestDescriptionHelper(*settings.Digest))
+	}
+}
+
+func DigestDescriptionHelper(digest DigestSettings) string {
+	return fmt.Sprintf(
+		"new papers from all alerts are posted in <#%s> every %s at %02d:%02d %s",
+		digest.ChannelID, digest.Weekday, digest.TimeOfDay/60, digest.TimeOfDay%60, digest.Timezone,
+	)
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"scholar-bot/apihandlers"
+	"scholar-bot/exporters"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func ExportFormatChoicesHelper() []*discordgo.ApplicationCommandOptionChoice {
+	var choices []*discordgo.ApplicationCommandOptionChoice
+	for value, format := range exporters.Formats {
+		choices = append(choices, &discordgo.ApplicationCommandOptionChoice{
+			Name: format.Name, Value: value,
+		})
+	}
+	sort.Slice(choices, func(i, j int) bool { return choices[i].Name < choices[j].Name })
+	return choices
+}
+
+// ExportFormatNamesHelper lists the export formats by name, sorted.
+func ExportFormatNamesHelper() []string {
+	names := make([]string, 0, len(exporters.Formats))
+	for name := range exporters.Formats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ExportFileHelper renders studies in the format and wraps them as an attachment.
+func ExportFileHelper(studies []apihandlers.StudyStruct, formatName string) (*discordgo.File, error) {
+	format, ok := exporters.Formats[formatName]
+	if !ok {
+		return nil, fmt.Errorf("unknown export format %q", formatName)
+	}
+	exported, err := format.Export(studies)
+	if err != nil {
+		return nil, err
+	}
+	return &discordgo.File{
+		Name:        fmt.Sprintf("studies.%s", format.Extension),
+		ContentType: format.ContentType,
+		Reader:      strings.NewReader(exported),
+	}, nil
+}
+
+func ExportCommandHandler(ctx context.Context, botSession *discordgo.Session, botInteraction *discordgo.InteractionCreate) {
+	options := botInteraction.ApplicationCommandData().Options
+	optionMap := make(
+		map[string]*discordgo.ApplicationCommandInteractionDataOption,
+		len(options),
+	)
+	for _, opt := range options {
+		optionMap[opt.Name] = opt
+	}
+
+	query, ok := optionMap["google"]
+	if !ok {
+		RespondHelper(ctx, botSession, botInteraction,
+			&discordgo.InteractionResponse{
+				Type: discordgo.InteractionResponseChannelMessageWithSource,
+				Data: &discordgo.InteractionResponseData{
+					Content: "An error happened when retrieving the query",
+					Flags:   1 << 6,
+				},
+			})
+		return
+	}
+
+	formatName := "bibtex"
+	if format, ok := optionMap["format"]; ok {
+		formatName = format.StringValue()
+	}
+	settings := GuildSettingsHelper(botInteraction.GuildID)
+	sourceName, source := SourceOptionHelper(optionMap, settings)
+
+	var studies []apihandlers.StudyStruct
+	var err error
+	if topTen, ok := optionMap["topten"]; ok && topTen.BoolValue() {
+		var studySlice *[]apihandlers.StudyStruct
+		studySlice, err = source.QueryTopTen(ctx, query.StringValue(), YearInputHelper(optionMap, settings))
+		if err == nil {
+			studies = ResultsPageHelper(*studySlice, settings)
+		}
+	} else {
+		var study *apihandlers.StudyStruct
+		study, err = source.QueryFirst(ctx, query.StringValue(), YearInputHelper(optionMap, settings))
+		if err == nil {
+			studies = []apihandlers.StudyStruct{*study}
+		}
+	}
+
+	var file *discordgo.File
+	if err == nil {
+		file, err = ExportFileHelper(studies, formatName)
+	}
+	if err != nil {
+		RespondHelper(ctx, botSession, botInteraction,
+			&discordgo.InteractionResponse{
+				Type: discordgo.InteractionResponseChannelMessageWithSource,
+				Data: &discordgo.InteractionResponseData{
+					Content: ErrorMessageHelper(err, source.Name()),
+					Flags:   1 << 6,
+				},
+			})
+		return
+	}
+
+	RespondHelper(ctx, botSession, botInteraction,
+		&discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{
+				Content: FallbackNoticeHelper(sourceName, studies[0].Source) + StudyListHelper(studies),
+				Files:   []*discordgo.File{file},
+				Flags:   ResultFlagsHelper(settings),
+			},
+		})
+}
+
+// ExportComponentHandler answers the "Export" button of a PubMed result with
+// the study attached in the chosen format, offering the other formats as buttons.
+func ExportComponentHandler(ctx context.Context, botSession *discordgo.Session, botInteraction *discordgo.InteractionCreate) {
+	// Custom ID is "export:<format>:<pmid>"
+	args := strings.SplitN(botInteraction.MessageComponentData().CustomID, ":", 3)
+	if len(args) != 3 {
+		RespondHelper(ctx, botSession, botInteraction,
+			&discordgo.InteractionResponse{
+				Type: discordgo.InteractionResponseChannelMessageWithSource,
+				Data: &discordgo.InteractionResponseData{
+					Content: "An error happened when exporting the study",
+					Flags:   1 << 6,
+				},
+			})
+		return
+	}
+	formatName, pmid := args[1], args[2]
+
+	studySlice, err := apihandlers.QueryPMCByIds(ctx, []string{pmid})
+	var file *discordgo.File
+	if err == nil {
+		file, err = ExportFileHelper(*studySlice, formatName)
+	}
+	if err != nil {
+		RespondHelper(ctx, botSession, botInteraction,
+			&discordgo.InteractionResponse{
+				Type: discordgo.InteractionResponseChannelMessageWithSource,
+				Data: &discordgo.InteractionResponseData{
+					Content: ErrorMessageHelper(err, "PubMed"),
+					Flags:   1 << 6,
+				},
+			})
+		return
+	}
+
+	var otherFormats []discordgo.MessageComponent
+	for _, choice := range ExportFormatChoicesHelper() {
+		if choice.Value == formatName {
+			continue
+		}
+		otherFormats = append(otherFormats, discordgo.Button{
+			Label:    choice.Name,
+			Style:    discordgo.SecondaryButton,
+			CustomID: fmt.Sprintf("export:%s:%s", choice.Value, pmid),
+		})
+	}
+
+	RespondHelper(ctx, botSession, botInteraction,
+		&discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{
+				Content: fmt.Sprintf("%s export", exporters.Formats[formatName].Name),
+				Files:   []*discordgo.File{file},
+				Components: []discordgo.MessageComponent{
+					discordgo.ActionsRow{Components: otherFormats},
+				},
+				Flags: 1 << 6,
+			},
+		})
+}
@@ -0,0 +1,101 @@
+package exporters
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"scholar-bot/apihandlers"
+)
+
+var bibtexEscaper = strings.NewReplacer(
+	`\`, `\textbackslash{}`,
+	`{`, `\{`,
+	`}`, `\}`,
+	`&`, `\&`,
+	`%`, `\%`,
+	`$`, `\$`,
+	`#`, `\#`,
+	`_`, `\_`,
+)
+
+// BibTeX exports studies as @article entries.
+func BibTeX(studies []apihandlers.StudyStruct) (string, error) {
+	var builder strings.Builder
+	usedKeys := make(map[string]int)
+	for _, study := range studies {
+		key := bibtexKey(study)
+		usedKeys[key]++
+		if usedKeys[key] > 1 {
+			key = fmt.Sprintf("%s%c", key, 'a'+usedKeys[key]-2)
+		}
+
+		fmt.Fprintf(&builder, "@article{%s,\n", key)
+		writeBibtexField(&builder, "title", study.Title)
+		writeBibtexField(&builder, "author", bibtexAuthors(study.AuthorList))
+		writeBibtexField(&builder, "journal", study.Venue)
+		writeBibtexField(&builder, "year", study.Year)
+		writeBibtexField(&builder, "month", study.Month)
+		writeBibtexField(&builder, "volume", study.Volume)
+		writeBibtexField(&builder, "number", study.Issue)
+		if start, end := study.PageRange(); end != "" {
+			writeBibtexField(&builder, "pages", start+"--"+end)
+		} else {
+			writeBibtexField(&builder, "pages", start)
+		}
+		writeBibtexField(&builder, "publisher", study.Publisher)
+		writeBibtexField(&builder, "doi", study.Doi)
+		writeBibtexField(&builder, "pmid", study.Pmid)
+		writeBibtexField(&builder, "url", study.Url)
+		builder.WriteString("}\n\n")
+	}
+	return builder.String(), nil
+}
+
+func writeBibtexField(builder *strings.Builder, name string, value string) {
+	if value == "" {
+		return
+	}
+	// Authors are already joined with " and " and must not be escaped twice
+	if name != "author" && name != "url" {
+		value = bibtexEscaper.Replace(value)
+	}
+	fmt.Fprintf(builder, "  %s = {%s},\n", name, value)
+}
+
+func bibtexAuthors(authors []apihandlers.Author) string {
+	var names []string
+	for _, author := range authors {
+		name := bibtexEscaper.Replace(author.LastName)
+		if given := author.GivenName(); given != "" {
+			name += ", " + bibtexEscaper.Replace(given)
+		}
+		names = append(names, name)
+	}
+	return strings.Join(names, " and ")
+}
+
+// bibtexKey builds a citation key like "smith2019deep".
+func bibtexKey(study apihandlers.StudyStruct) string {
+	var key string
+	if len(study.AuthorList) != 0 {
+		key = study.AuthorList[0].LastName
+	}
+	key += study.Year
+	for _, word := range strings.Fields(study.Title) {
+		if len(word) > 3 {
+			key += word
+			break
+		}
+	}
+	key = strings.Map(func(r rune) rune {
+		if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			return unicode.ToLower(r)
+		}
+		return -1
+	}, key)
+	if key == "" {
+		key = "study"
+	}
+	return key
+}
@@ -0,0 +1,107 @@
+package exporters
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"scholar-bot/apihandlers"
+)
+
+type cslName struct {
+	Family string `json:"family,omitempty"`
+	Given  string `json:"given,omitempty"`
+}
+
+type cslDate struct {
+	DateParts [][]int `json:"date-parts"`
+}
+
+type cslItem struct {
+	Id             string    `json:"id"`
+	Type           string    `json:"type"`
+	Title          string    `json:"title,omitempty"`
+	Author         []cslName `json:"author,omitempty"`
+	ContainerTitle string    `json:"container-title,omitempty"`
+	JournalAbbrev  string    `json:"container-title-short,omitempty"`
+	Issued         *cslDate  `json:"issued,omitempty"`
+	Volume         string    `json:"volume,omitempty"`
+	Issue          string    `json:"issue,omitempty"`
+	Page           string    `json:"page,omitempty"`
+	Publisher      string    `json:"publisher,omitempty"`
+	Abstract       string    `json:"abstract,omitempty"`
+	Doi            string    `json:"DOI,omitempty"`
+	Pmid           string    `json:"PMID,omitempty"`
+	Pmcid          string    `json:"PMCID,omitempty"`
+	Url            string    `json:"URL,omitempty"`
+}
+
+// CSLJSON exports studies as a CSL-JSON array, as used by Zotero and pandoc.
+func CSLJSON(studies []apihandlers.StudyStruct) (string, error) {
+	items := make([]cslItem, 0, len(studies))
+	for i, study := range studies {
+		item := cslItem{
+			Id:             bibtexKey(study),
+			Type:           "article-journal",
+			Title:          study.Title,
+			ContainerTitle: study.Venue,
+			JournalAbbrev:  study.JournalAbbrev,
+			Volume:         study.Volume,
+			Issue:          study.Issue,
+			Page:           study.Pages,
+			Publisher:      study.Publisher,
+			Abstract:       study.Abstract,
+			Doi:            study.Doi,
+			Pmid:           study.Pmid,
+			Pmcid:          study.Pmcid,
+			Url:            study.Url,
+		}
+		if study.Pmid != "" {
+			item.Id = "pmid:" + study.Pmid
+		} else {
+			item.Id = fmt.Sprintf("%s-%d", item.Id, i+1)
+		}
+		for _, author := range study.AuthorList {
+			item.Author = append(item.Author, cslName{Family: author.LastName, Given: author.GivenName()})
+		}
+		if year, err := strconv.Atoi(study.Year); err == nil {
+			item.Issued = &cslDate{DateParts: [][]int{{year}}}
+			if month := monthNumber(study.Month); month != 0 {
+				item.Issued.DateParts[0] = append(item.Issued.DateParts[0], month)
+			}
+		}
+		items = append(items, item)
+	}
+
+	data, err := json.MarshalIndent(items, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("error encoding CSL-JSON: %w", err)
+	}
+	return string(data), nil
+}
+
+// monthNumber understands both PubMed's "Jan" and numeric months.
+func monthNumber(month string) int {
+	if number, err := strconv.Atoi(month); err == nil && number >= 1 && number <= 12 {
+		return number
+	}
+	for i, name := range []string{"jan", "feb", "mar", "apr", "may", "jun", "jul", "aug", "sep", "oct", "nov", "dec"} {
+		if len(month) >= 3 && equalFoldPrefix(month, name) {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+func equalFoldPrefix(value string, prefix string) bool {
+	for i := 0; i < len(prefix); i++ {
+		c := value[i]
+		if c >= 'A' && c <= 'Z' {
+			c += 'a' - 'A'
+		}
+		if c != prefix[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,79 @@
+package exporters
+
+import (
+	"encoding/xml"
+	"fmt"
+
+	"scholar-bot/apihandlers"
+)
+
+// The EndNote XML schema wraps most values in a <style> element.
+type endnoteText struct {
+	Style string `xml:"style"`
+}
+
+type endnoteRecord struct {
+	RefType struct {
+		Name  string `xml:"name,attr"`
+		Value int    `xml:",chardata"`
+	} `xml:"ref-type"`
+	Authors  []endnoteText `xml:"contributors>authors>author"`
+	Title    *endnoteText  `xml:"titles>title,omitempty"`
+	Journal  *endnoteText  `xml:"titles>secondary-title,omitempty"`
+	Pages    *endnoteText  `xml:"pages,omitempty"`
+	Volume   *endnoteText  `xml:"volume,omitempty"`
+	Number   *endnoteText  `xml:"number,omitempty"`
+	Year     *endnoteText  `xml:"dates>year,omitempty"`
+	Doi      *endnoteText  `xml:"electronic-resource-num,omitempty"`
+	Pmid     *endnoteText  `xml:"accession-num,omitempty"`
+	Urls     []endnoteText `xml:"urls>related-urls>url"`
+	Abstract *endnoteText  `xml:"abstract,omitempty"`
+}
+
+type endnoteXml struct {
+	XMLName xml.Name        `xml:"xml"`
+	Records []endnoteRecord `xml:"records>record"`
+}
+
+func optionalEndnoteText(value string) *endnoteText {
+	if value == "" {
+		return nil
+	}
+	return &endnoteText{Style: value}
+}
+
+// EndNoteXML exports studies in EndNote's XML import format.
+func EndNoteXML(studies []apihandlers.StudyStruct) (string, error) {
+	var document endnoteXml
+	for _, study := range studies {
+		var record endnoteRecord
+		record.RefType.Name = "Journal Article"
+		record.RefType.Value = 17
+		for _, author := range study.AuthorList {
+			name := author.LastName
+			if given := author.GivenName(); given != "" {
+				name += ", " + given
+			}
+			record.Authors = append(record.Authors, endnoteText{Style: name})
+		}
+		record.Title = optionalEndnoteText(study.Title)
+		record.Journal = optionalEndnoteText(study.Venue)
+		record.Pages = optionalEndnoteText(study.Pages)
+		record.Volume = optionalEndnoteText(study.Volume)
+		record.Number = optionalEndnoteText(study.Issue)
+		record.Year = optionalEndnoteText(study.Year)
+		record.Doi = optionalEndnoteText(study.Doi)
+		record.Pmid = optionalEndnoteText(study.Pmid)
+		record.Abstract = optionalEndnoteText(study.Abstract)
+		if study.Url != "" {
+			record.Urls = append(record.Urls, endnoteText{Style: study.Url})
+		}
+		document.Records = append(document.Records, record)
+	}
+
+	data, err := xml.MarshalIndent(document, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("error encoding EndNote XML: %w", err)
+	}
+	return xml.Header + string(data), nil
+}
@@ -0,0 +1,41 @@
+package exporters
+
+import (
+	"scholar-bot/apihandlers"
+)
+
+// Format is a reference manager file format studies can be exported to.
+type Format struct {
+	Name        string
+	Extension   string
+	ContentType string
+	Export      func(studies []apihandlers.StudyStruct) (string, error)
+}
+
+// Formats holds every export format keyed by its command choice value.
+var Formats = map[string]Format{
+	"bibtex": {
+		Name:        "BibTeX",
+		Extension:   "bib",
+		ContentType: "application/x-bibtex",
+		Export:      BibTeX,
+	},
+	"ris": {
+		Name:        "RIS",
+		Extension:   "ris",
+		ContentType: "application/x-research-info-systems",
+		Export:      RIS,
+	},
+	"csljson": {
+		Name:        "CSL-JSON",
+		Extension:   "json",
+		ContentType: "application/vnd.citationstyles.csl+json",
+		Export:      CSLJSON,
+	},
+	"endnote": {
+		Name:        "EndNote XML",
+		Extension:   "xml",
+		ContentType: "application/xml",
+		Export:      EndNoteXML,
+	},
+}
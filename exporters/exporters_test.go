@@ -0,0 +1,183 @@
+package exporters
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"reflect"
+	"strings"
+	"testing"
+
+	"scholar-bot/apihandlers"
+)
+
+var testStudy = apihandlers.StudyStruct{
+	Title:  "CRISPR editing of {sickle} cell & disease",
+	Source: "pubmed",
+	AuthorList: []apihandlers.Author{
+		{LastName: "Smith", ForeName: "John Adam", Initials: "JA"},
+		{LastName: "Doe", Initials: "B"},
+	},
+	Venue:         "The New England journal of medicine",
+	JournalAbbrev: "N Engl J Med",
+	Year:          "2021",
+	Month:         "Jan",
+	Volume:        "384",
+	Issue:         "3",
+	Pages:         "252-260",
+	Doi:           "10.1056/NEJMoa2031054",
+	Pmid:          "33283989",
+	Url:           "https://pubmed.ncbi.nlm.nih.gov/33283989/",
+	Abstract:      "Abstract.",
+}
+
+func TestBibTeX(t *testing.T) {
+	got, err := BibTeX([]apihandlers.StudyStruct{testStudy})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `@article{smith2021crispr,
+  title = {CRISPR editing of \{sickle\} cell \& disease},
+  author = {Smith, John Adam and Doe, B.},
+  journal = {The New England journal of medicine},
+  year = {2021},
+  month = {Jan},
+  volume = {384},
+  number = {3},
+  pages = {252--260},
+  doi = {10.1056/NEJMoa2031054},
+  pmid = {33283989},
+  url = {https://pubmed.ncbi.nlm.nih.gov/33283989/},
+}
+
+`
+	if got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestBibTeXKeys(t *testing.T) {
+	tests := []struct {
+		studies []apihandlers.StudyStruct
+		want    []string
+	}{
+		{
+			studies: []apihandlers.StudyStruct{testStudy, testStudy, testStudy},
+			want:    []string{"smith2021crispr", "smith2021crispra", "smith2021crisprb"},
+		},
+		{
+			studies: []apihandlers.StudyStruct{{Title: "A of the"}},
+			want:    []string{"study"},
+		},
+	}
+	for _, test := range tests {
+		output, err := BibTeX(test.studies)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var keys []string
+		for _, line := range strings.Split(output, "\n") {
+			if key, ok := strings.CutPrefix(line, "@article{"); ok {
+				keys = append(keys, strings.TrimSuffix(key, ","))
+			}
+		}
+		if !reflect.DeepEqual(keys, test.want) {
+			t.Errorf("keys = %q, want %q", keys, test.want)
+		}
+	}
+}
+
+func TestRIS(t *testing.T) {
+	got, err := RIS([]apihandlers.StudyStruct{testStudy})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `TY  - JOUR
+TI  - CRISPR editing of {sickle} cell & disease
+AU  - Smith, John Adam
+AU  - Doe, B.
+JO  - The New England journal of medicine
+J2  - N Engl J Med
+PY  - 2021
+VL  - 384
+IS  - 3
+SP  - 252
+EP  - 260
+AB  - Abstract.
+DO  - 10.1056/NEJMoa2031054
+AN  - PMID:33283989
+UR  - https://pubmed.ncbi.nlm.nih.gov/33283989/
+ER  - 
+
+`
+	if got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestCSLJSON(t *testing.T) {
+	output, err := CSLJSON([]apihandlers.StudyStruct{testStudy})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var items []cslItem
+	if err := json.Unmarshal([]byte(output), &items); err != nil {
+		t.Fatal(err)
+	}
+	want := []cslItem{{
+		Id:             "pmid:33283989",
+		Type:           "article-journal",
+		Title:          testStudy.Title,
+		Author:         []cslName{{Family: "Smith", Given: "John Adam"}, {Family: "Doe", Given: "B."}},
+		ContainerTitle: testStudy.Venue,
+		JournalAbbrev:  testStudy.JournalAbbrev,
+		Issued:         &cslDate{DateParts: [][]int{{2021, 1}}},
+		Volume:         "384",
+		Issue:          "3",
+		Page:           "252-260",
+		Abstract:       "Abstract.",
+		Doi:            testStudy.Doi,
+		Pmid:           testStudy.Pmid,
+		Url:            testStudy.Url,
+	}}
+	if !reflect.DeepEqual(items, want) {
+		t.Errorf("got  %+v\nwant %+v", items, want)
+	}
+}
+
+func TestEndNoteXML(t *testing.T) {
+	output, err := EndNoteXML([]apihandlers.StudyStruct{testStudy, {Title: "Second"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var parsed struct {
+		Records []struct {
+			Authors []string `xml:"contributors>authors>author>style"`
+			Title   string   `xml:"titles>title>style"`
+			Year    string   `xml:"dates>year>style"`
+		} `xml:"records>record"`
+	}
+	if err := xml.Unmarshal([]byte(output), &parsed); err != nil {
+		t.Fatal(err)
+	}
+	if len(parsed.Records) != 2 {
+		t.Fatalf("got %d records, want 2", len(parsed.Records))
+	}
+	record := parsed.Records[0]
+	if record.Title != testStudy.Title || record.Year != "2021" {
+		t.Errorf("title, year = %q, %q", record.Title, record.Year)
+	}
+	if want := []string{"Smith, John Adam", "Doe, B."}; !reflect.DeepEqual(record.Authors, want) {
+		t.Errorf("authors = %q, want %q", record.Authors, want)
+	}
+}
+
+func TestFormats(t *testing.T) {
+	for name, format := range Formats {
+		if format.Name == "" || format.Extension == "" || format.ContentType == "" || format.Export == nil {
+			t.Errorf("format %q is incomplete: %+v", name, format)
+		}
+		if _, err := format.Export(nil); err != nil {
+			t.Errorf("%s of no studies: %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package exporters
+
+import (
+	"fmt"
+	"strings"
+
+	"scholar-bot/apihandlers"
+)
+
+// RIS exports studies as RIS records, understood by most reference managers.
+func RIS(studies []apihandlers.StudyStruct) (string, error) {
+	var builder strings.Builder
+	for _, study := range studies {
+		writeRisTag(&builder, "TY", "JOUR")
+		writeRisTag(&builder, "TI", study.Title)
+		for _, author := range study.AuthorList {
+			name := author.LastName
+			if given := author.GivenName(); given != "" {
+				name += ", " + given
+			}
+			writeRisTag(&builder, "AU", name)
+		}
+		writeRisTag(&builder, "JO", study.Venue)
+		writeRisTag(&builder, "J2", study.JournalAbbrev)
+		writeRisTag(&builder, "PY", study.Year)
+		writeRisTag(&builder, "VL", study.Volume)
+		writeRisTag(&builder, "IS", study.Issue)
+		start, end := study.PageRange()
+		writeRisTag(&builder, "SP", start)
+		writeRisTag(&builder, "EP", end)
+		writeRisTag(&builder, "PB", study.Publisher)
+		writeRisTag(&builder, "AB", study.Abstract)
+		writeRisTag(&builder, "DO", study.Doi)
+		if study.Pmid != "" {
+			writeRisTag(&builder, "AN", "PMID:"+study.Pmid)
+		}
+		writeRisTag(&builder, "UR", study.Url)
+		builder.WriteString("ER  - \n\n")
+	}
+	return builder.String(), nil
+}
+
+func writeRisTag(builder *strings.Builder, tag string, value string) {
+	value = strings.Join(strings.Fields(value), " ")
+	if value == "" {
+		return
+	}
+	fmt.Fprintf(builder, "%s  - %s\n", tag, value)
+}
@@ -0,0 +1,131 @@
+package feeds
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strings"
+	"time"
+
+	"scholar-bot/apihandlers"
+)
+
+// Item is a study as it appeared in a feed, with when it was found.
+type Item struct {
+	Study   apihandlers.StudyStruct `json:"study"`
+	FoundAt time.Time               `json:"found_at"`
+}
+
+// Feed describes an Atom feed of studies.
+type Feed struct {
+	Id       string
+	Title    string
+	Subtitle string
+	SelfUrl  string
+	Items    []Item
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+}
+
+type atomPerson struct {
+	Name string `xml:"name"`
+}
+
+type atomEntry struct {
+	Id       string        `xml:"id"`
+	Title    string        `xml:"title"`
+	Updated  string        `xml:"updated"`
+	Links    []atomLink    `xml:"link"`
+	Authors  []atomPerson  `xml:"author"`
+	Summary  string        `xml:"summary,omitempty"`
+	Category *atomCategory `xml:"category,omitempty"`
+}
+
+type atomCategory struct {
+	Term string `xml:"term,attr"`
+}
+
+type atomFeed struct {
+	XMLName  xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	Id       string      `xml:"id"`
+	Title    string      `xml:"title"`
+	Subtitle string      `xml:"subtitle,omitempty"`
+	Updated  string      `xml:"updated"`
+	Links    []atomLink  `xml:"link"`
+	Author   atomPerson  `xml:"author"`
+	Entries  []atomEntry `xml:"entry"`
+}
+
+// EntryId returns a stable Atom id for a study, based on its PMID or DOI
+// when it has one so the same paper keeps its id across feeds and runs.
+func EntryId(study apihandlers.StudyStruct) string {
+	switch {
+	case study.Pmid != "":
+		return "info:pmid/" + study.Pmid
+	case study.Doi != "":
+		return "info:doi/" + study.Doi
+	case study.CiteId != "":
+		return "tag:scholar-bot,2024:gs:" + study.CiteId
+	}
+	return study.Url
+}
+
+// Atom renders the feed as an Atom 1.0 document, newest items first as
+// they are given.
+func Atom(feed Feed) ([]byte, error) {
+	document := atomFeed{
+		Id:       feed.Id,
+		Title:    feed.Title,
+		Subtitle: feed.Subtitle,
+		Author:   atomPerson{Name: "scholar-bot"},
+		Links: []atomLink{
+			{Href: feed.SelfUrl, Rel: "self", Type: "application/atom+xml"},
+		},
+	}
+
+	var updated time.Time
+	for _, item := range feed.Items {
+		if item.FoundAt.After(updated) {
+			updated = item.FoundAt
+		}
+		entry := atomEntry{
+			Id:      EntryId(item.Study),
+			Title:   item.Study.Title,
+			Updated: item.FoundAt.UTC().Format(time.RFC3339),
+			Summary: item.Study.Abstract,
+		}
+		if item.Study.Source != "" {
+			entry.Category = &atomCategory{Term: item.Study.Source}
+		}
+		if item.Study.Url != "" {
+			entry.Links = append(entry.Links, atomLink{Href: item.Study.Url, Rel: "alternate"})
+		}
+		if item.Study.PdfUrl != "" {
+			entry.Links = append(entry.Links, atomLink{Href: item.Study.PdfUrl, Rel: "related", Type: "application/pdf"})
+		}
+		if item.Study.Doi != "" {
+			entry.Links = append(entry.Links, atomLink{Href: "https://doi.org/" + item.Study.Doi, Rel: "related"})
+		}
+		if len(item.Study.AuthorList) != 0 {
+			for _, author := range item.Study.AuthorList {
+				entry.Authors = append(entry.Authors, atomPerson{Name: strings.TrimSpace(author.GivenName() + " " + author.LastName)})
+			}
+		} else if item.Study.Authors != "" {
+			entry.Authors = append(entry.Authors, atomPerson{Name: item.Study.Authors})
+		}
+		document.Entries = append(document.Entries, entry)
+	}
+	if updated.IsZero() {
+		updated = time.Now()
+	}
+	document.Updated = updated.UTC().Format(time.RFC3339)
+
+	data, err := xml.MarshalIndent(document, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("error encoding Atom feed: %w", err)
+	}
+	return append([]byte(xml.Header), data...), nil
+}
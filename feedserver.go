@@ -0,0 +1,90 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"scholar-bot/apihandlers"
+	"scholar-bot/feeds"
+)
+
+// feedBaseUrl is the public URL the feed server is reached at, empty when
+// feeds are disabled.
+var feedBaseUrl string
+
+func FeedUrlHelper(alert Alert) string {
+	return fmt.Sprintf("%s/feeds/%s.atom", feedBaseUrl, alert.FeedToken)
+}
+
+// alertByFeedToken finds the alert a feed URL points at.
+func alertByFeedToken(token string) (Alert, bool, error) {
+	records, err := alerts.List("")
+	if err != nil {
+		return Alert{}, false, err
+	}
+	for _, record := range records {
+		if record.Value.FeedToken != "" && record.Value.FeedToken == token {
+			return record.Value, true, nil
+		}
+	}
+	return Alert{}, false, nil
+}
+
+func feedHandler(w http.ResponseWriter, r *http.Request) {
+	token, ok := strings.CutSuffix(r.PathValue("file"), ".atom")
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	alert, ok, err := alertByFeedToken(token)
+	if err != nil {
+		slog.Error("cannot load feed", "token", token, "err", err)
+		http.Error(w, "error loading feed", http.StatusInternalServerError)
+		return
+	}
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	items := slices.Clone(alert.Recent)
+	slices.Reverse(items)
+	feed, err := feeds.Atom(feeds.Feed{
+		Id:       "tag:scholar-bot,2024:alert:" + alert.GuildID + ":" + alert.ID,
+		Title:    fmt.Sprintf("%s: %s", apihandlers.Sources[alert.Source].Name(), alert.Query),
+		Subtitle: fmt.Sprintf("New papers found by scholar-bot alert %s", alert.ID),
+		SelfUrl:  FeedUrlHelper(alert),
+		Items:    items,
+	})
+	if err != nil {
+		slog.Error("cannot render feed", "token", token, "err", err)
+		http.Error(w, "error rendering feed", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/atom+xml; charset=utf-8")
+	w.Write(feed)
+}
+
+// StartFeedServer serves the alert feeds on addr in the background.
+func StartFeedServer(addr string) *http.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /feeds/{file}", feedHandler)
+	server := &http.Server{
+		Addr:              addr,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		slog.Info("serving feeds", "addr", addr)
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			slog.Error("feed server stopped", "err", err)
+		}
+	}()
+	return server
+}
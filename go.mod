@@ -3,6 +3,7 @@ module scholar-bot
 go 1.22.1
 
 require (
+	github.com/BurntSushi/toml v1.5.0
 	github.com/PuerkitoBio/goquery v1.9.2
 	github.com/bwmarrin/discordgo v0.28.1
 )
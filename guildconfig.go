@@ -0,0 +1,256 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	"scholar-bot/apihandlers"
+	"scholar-bot/citation"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// Bot-wide defaults used when a guild hasn't configured its own.
+const (
+	defaultSource         = "pubmed"
+	defaultMinYear        = 2015
+	defaultResultsPerPage = 10
+)
+
+var (
+	minResultsPerPage float64 = 1
+	maxResultsPerPage float64 = 10
+	// minMinYear is the earliest year /config minyear accepts, the latest
+	// being the current year
+	minMinYear float64 = 1800
+)
+
+// maxMinYearHelper returns the latest year /config minyear accepts.
+func maxMinYearHelper() float64 {
+	return float64(time.Now().Year())
+}
+
+// unconfigurableCommands can't be disabled, so admins can't lock themselves out.
+var unconfigurableCommands = []string{"config", "admin"}
+
+// GuildSettingsHelper loads the guild's settings with the bot defaults
+// filled in. It never fails: on a storage error the defaults are used.
+func GuildSettingsHelper(guildID string) GuildSettings {
+	var settings GuildSettings
+	if guildID != "" {
+		var err error
+		settings, _, err = guildSettings.Get(guildID)
+		if err != nil {
+			slog.Error("cannot load guild settings", "guild", guildID, "err", err)
+		}
+	}
+	if settings.DefaultSource == "" {
+		settings.DefaultSource = defaultSource
+	}
+	if settings.MinYear == 0 {
+		settings.MinYear = defaultMinYear
+	}
+	if settings.ResultsPerPage == 0 {
+		settings.ResultsPerPage = defaultResultsPerPage
+	}
+	return settings
+}
+
+// ResultFlagsHelper returns the flags of result messages, making them
+// visible only to the user on guilds that asked for it.
+func ResultFlagsHelper(settings GuildSettings) discordgo.MessageFlags {
+	if settings.Ephemeral {
+		return discordgo.MessageFlagsEphemeral
+	}
+	return 0
+}
+
+// ResultsPageHelper cuts a result list down to the guild's page size.
+func ResultsPageHelper(studies []apihandlers.StudyStruct, settings GuildSettings) []apihandlers.StudyStruct {
+	if len(studies) > settings.ResultsPerPage {
+		return studies[:settings.ResultsPerPage]
+	}
+	return studies
+}
+
+// SourceOptionHelper picks the requested source or the guild's default,
+// with Scholar going through its fallback.
+func SourceOptionHelper(
+	optionMap map[string]*discordgo.ApplicationCommandInteractionDataOption,
+	settings GuildSettings,
+) (string, apihandlers.Source) {
+	sourceName := settings.DefaultSource
+	if source, ok := optionMap["source"]; ok {
+		sourceName = source.StringValue()
+	}
+	if sourceName == "scholar" {
+		return sourceName, ScholarSourceHelper()
+	}
+	return sourceName, apihandlers.Sources[sourceName]
+}
+
+// SourceChoicesHelper lists the enabled sources for command options.
+func SourceChoicesHelper() []*discordgo.ApplicationCommandOptionChoice {
+	var choices []*discordgo.ApplicationCommandOptionChoice
+	for _, name := range []string{"pubmed", "scholar"} {
+		if apihandlers.SourceEnabled(name) {
+			choices = append(choices, &discordgo.ApplicationCommandOptionChoice{
+				Name: apihandlers.Sources[name].Name(), Value: name,
+			})
+		}
+	}
+	return choices
+}
+
+// CommandDisabledHelper reports whether the guild turned the command off.
+func CommandDisabledHelper(guildID string, commandName string) bool {
+	if guildID == "" || slices.Contains(unconfigurableCommands, commandName) {
+		return false
+	}
+	return slices.Contains(GuildSettingsHelper(guildID).DisabledCommands, commandName)
+}
+
+// configurableCommandHelper reports whether name is a command /config can
+// turn on and off.
+func configurableCommandHelper(name string) bool {
+	if slices.Contains(unconfigurableCommands, name) {
+		return false
+	}
+	return slices.ContainsFunc(CommandsHelper(), func(command *discordgo.ApplicationCommand) bool {
+		return command.Name == name
+	})
+}
+
+func GuildSettingsDescriptionHelper(settings GuildSettings) string {
+	style := "Vancouver for PubMed, APA otherwise"
+	if settings.CitationStyle != "" {
+		style = citation.Styles[settings.CitationStyle].Name
+	}
+	visibility := "public"
+	if settings.Ephemeral {
+		visibility = "only visible to the user"
+	}
+	disabled := "none"
+	if len(settings.DisabledCommands) != 0 {
+		disabled = "/" + strings.Join(settings.DisabledCommands, ", /")
+	}
+	exempt := "none"
+	if len(settings.ExemptRoles) != 0 {
+		exempt = "<@&" + strings.Join(settings.ExemptRoles, ">, <@&") + ">"
+	}
+	return fmt.Sprintf(
+		"Default source: %s\nMinimum year: %d\nResults per page: %d\nCitation style: %s\nReplies: %s\nDisabled commands: %s\nRoles exempt from rate limits: %s",
+		apihandlers.Sources[settings.DefaultSource].Name(),
+		settings.MinYear,
+		settings.ResultsPerPage,
+		style,
+		visibility,
+		disabled,
+		exempt,
+	)
+}
+
+func ConfigCommandHandler(ctx context.Context, botSession *discordgo.Session, botInteraction *discordgo.InteractionCreate) {
+	subcommand := botInteraction.ApplicationCommandData().Options[0]
+	optionMap := make(
+		map[string]*discordgo.ApplicationCommandInteractionDataOption,
+		len(subcommand.Options),
+	)
+	for _, opt := range subcommand.Options {
+		optionMap[opt.Name] = opt
+	}
+
+	if botInteraction.GuildID == "" {
+		EphemeralResponseHelper(ctx, botSession, botInteraction, "The configuration only exists in servers")
+		return
+	}
+	// Discord enforces the default permission, unless an admin overrode it
+	// for a role, so check again here
+	if botInteraction.Member == nil || botInteraction.Member.Permissions&discordgo.PermissionManageServer == 0 {
+		EphemeralResponseHelper(ctx, botSession, botInteraction, "You need the Manage Server permission to change the configuration")
+		return
+	}
+
+	if command, ok := optionMap["command"]; ok && !configurableCommandHelper(strings.TrimPrefix(command.StringValue(), "/")) {
+		EphemeralResponseHelper(
+			ctx,
+			botSession,
+			botInteraction,
+			fmt.Sprintf("`%s` is not a command that can be turned off", command.StringValue()),
+		)
+		return
+	}
+
+	// The bounds of the option were set when the commands were last synced
+	if minYear, ok := optionMap["minyear"]; ok &&
+		(float64(minYear.IntValue()) < minMinYear || float64(minYear.IntValue()) > maxMinYearHelper()) {
+		EphemeralResponseHelper(
+			ctx,
+			botSession,
+			botInteraction,
+			fmt.Sprintf("The minimum year must be between %.0f and %.0f", minMinYear, maxMinYearHelper()),
+		)
+		return
+	}
+
+	if subcommand.Name == "show" {
+		EphemeralResponseHelper(
+			ctx,
+			botSession,
+			botInteraction,
+			GuildSettingsDescriptionHelper(GuildSettingsHelper(botInteraction.GuildID)),
+		)
+		return
+	}
+
+	err := guildSettings.Update(botInteraction.GuildID, func(settings *GuildSettings, exists bool) (bool, error) {
+		switch subcommand.Name {
+		case "source":
+			settings.DefaultSource = optionMap["source"].StringValue()
+		case "minyear":
+			settings.MinYear = int(optionMap["minyear"].IntValue())
+		case "results":
+			settings.ResultsPerPage = int(optionMap["count"].IntValue())
+		case "style":
+			settings.CitationStyle = optionMap["style"].StringValue()
+		case "replies":
+			settings.Ephemeral = optionMap["private"].BoolValue()
+		case "command":
+			name := strings.TrimPrefix(optionMap["command"].StringValue(), "/")
+			settings.DisabledCommands = slices.DeleteFunc(settings.DisabledCommands, func(disabled string) bool {
+				return disabled == name
+			})
+			if !optionMap["enabled"].BoolValue() {
+				settings.DisabledCommands = append(settings.DisabledCommands, name)
+			}
+		case "exempt":
+			role := optionMap["role"].RoleValue(nil, "").ID
+			settings.ExemptRoles = slices.DeleteFunc(settings.ExemptRoles, func(exempt string) bool {
+				return exempt == role
+			})
+			if optionMap["exempt"].BoolValue() {
+				settings.ExemptRoles = append(settings.ExemptRoles, role)
+			}
+		case "reset":
+			// Keep the digest schedule, it has its own command
+			*settings = GuildSettings{Digest: settings.Digest}
+		}
+		return false, nil
+	})
+	if err != nil {
+		apihandlers.Logger(ctx).Error("cannot save guild settings", "err", err)
+		EphemeralResponseHelper(ctx, botSession, botInteraction, "An error happened when saving the configuration")
+		return
+	}
+
+	EphemeralResponseHelper(
+		ctx,
+		botSession,
+		botInteraction,
+		"Configuration saved\n"+GuildSettingsDescriptionHelper(GuildSettingsHelper(botInteraction.GuildID)),
+	)
+}
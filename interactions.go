@@ -0,0 +1,166 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"scholar-bot/apihandlers"
+	"scholar-bot/config"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// interactionTokenLifetime is how long Discord accepts responses to an
+// interaction. Searches still queued after that are cancelled.
+const interactionTokenLifetime = 15 * time.Minute
+
+type interactionStateKey struct{}
+
+// interactionState remembers whether the response to an interaction was
+// deferred because its search had to queue.
+type interactionState struct {
+	mu        sync.Mutex
+	responded bool
+	deferred  bool
+}
+
+// InteractionContextHelper returns the context handlers of the interaction
+// run with: its queries are logged with the interaction's ID, queue fairly
+// against other guilds' and are cancelled when its token expires.
+func InteractionContextHelper(
+	ctx context.Context,
+	botSession *discordgo.Session,
+	botInteraction *discordgo.InteractionCreate,
+) (context.Context, context.CancelFunc) {
+	ctx = InteractionLoggerHelper(ctx, botInteraction)
+
+	state := &interactionState{}
+	ctx = context.WithValue(ctx, interactionStateKey{}, state)
+
+	owner := botInteraction.GuildID
+	if owner == "" {
+		owner = "user:" + InteractionUserHelper(botInteraction).ID
+	}
+	ctx = apihandlers.ContextWithQueue(ctx, owner, func(waiting int) {
+		QueueNoticeHelper(ctx, botSession, botInteraction, state, waiting)
+	})
+
+	createdAt, err := discordgo.SnowflakeTimestamp(botInteraction.ID)
+	if err != nil {
+		createdAt = time.Now()
+	}
+	return context.WithDeadline(ctx, createdAt.Add(interactionTokenLifetime))
+}
+
+// QueueNoticeHelper defers the response of an interaction whose search has
+// to wait, so Discord doesn't give up on it, and tells the user how busy
+// the bot is.
+func QueueNoticeHelper(
+	ctx context.Context,
+	botSession *discordgo.Session,
+	botInteraction *discordgo.InteractionCreate,
+	state *interactionState,
+	waiting int,
+) {
+	state.mu.Lock()
+	defer state.mu.Unlock()
+	if state.responded || state.deferred {
+		return
+	}
+	err := botSession.InteractionRespond(botInteraction.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Flags: ResultFlagsHelper(GuildSettingsHelper(botInteraction.GuildID)),
+		},
+	})
+	if err != nil {
+		apihandlers.Logger(ctx).Warn("cannot defer response", "err", err)
+		return
+	}
+	state.deferred = true
+
+	content := "⏳ The bot is busy, your search is queued behind 1 other"
+	if waiting > 2 {
+		content = fmt.Sprintf("⏳ The bot is busy, your search is queued behind %d others", waiting-1)
+	} else if waiting <= 1 {
+		content = "⏳ The bot is busy, your search will start shortly"
+	}
+	_, err = botSession.FollowupMessageCreate(botInteraction.Interaction, false, &discordgo.WebhookParams{
+		Content: content,
+		Flags:   discordgo.MessageFlagsEphemeral,
+	})
+	if err != nil {
+		apihandlers.Logger(ctx).Warn("cannot send queue notice", "err", err)
+	}
+}
+
+// RespondHelper answers the interaction, editing the deferred response
+// instead when the search had to queue.
+func RespondHelper(
+	ctx context.Context,
+	botSession *discordgo.Session,
+	botInteraction *discordgo.InteractionCreate,
+	response *discordgo.InteractionResponse,
+) error {
+	state, _ := ctx.Value(interactionStateKey{}).(*interactionState)
+	if state == nil {
+		return botSession.InteractionRespond(botInteraction.Interaction, response)
+	}
+
+	state.mu.Lock()
+	defer state.mu.Unlock()
+	if !state.deferred {
+		state.responded = true
+		return botSession.InteractionRespond(botInteraction.Interaction, response)
+	}
+
+	data := response.Data
+	if data == nil {
+		data = &discordgo.InteractionResponseData{}
+	}
+	if data.Flags&discordgo.MessageFlagsEphemeral != 0 {
+		// Only the user should see this, the deferred response may be public
+		botSession.InteractionResponseDelete(botInteraction.Interaction)
+		_, err := botSession.FollowupMessageCreate(botInteraction.Interaction, true, &discordgo.WebhookParams{
+			Content:    data.Content,
+			Embeds:     data.Embeds,
+			Components: data.Components,
+			Files:      data.Files,
+			Flags:      discordgo.MessageFlagsEphemeral,
+		})
+		return err
+	}
+	_, err := botSession.InteractionResponseEdit(botInteraction.Interaction, &discordgo.WebhookEdit{
+		Content:    &data.Content,
+		Embeds:     &data.Embeds,
+		Components: &data.Components,
+		Files:      data.Files,
+	})
+	return err
+}
+
+// EphemeralResponseHelper replies with a message only the user can see.
+func EphemeralResponseHelper(
+	ctx context.Context,
+	botSession *discordgo.Session,
+	botInteraction *discordgo.InteractionCreate,
+	content string,
+) {
+	RespondHelper(ctx, botSession, botInteraction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: content,
+			Flags:   discordgo.MessageFlagsEphemeral,
+		},
+	})
+}
+
+// SetupSchedulersHelper applies how many requests each source may run at
+// once.
+func SetupSchedulersHelper(sources config.SourcesConfig) {
+	for name, source := range sources.ByName() {
+		apihandlers.Schedulers[name].SetLimit(source.Concurrency)
+	}
+}
@@ -0,0 +1,268 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"time"
+
+	"scholar-bot/apihandlers"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+var minLibraryNumber float64 = 1
+
+// InteractionUserHelper returns who triggered the interaction, in a guild or a DM.
+func InteractionUserHelper(botInteraction *discordgo.InteractionCreate) *discordgo.User {
+	if botInteraction.Member != nil {
+		return botInteraction.Member.User
+	}
+	return botInteraction.User
+}
+
+// IdentifierSourceHelper names the source an identifier is looked up in.
+func IdentifierSourceHelper(identifier string) string {
+	if strings.HasPrefix(identifier, "gs:") {
+		return "Google Scholar"
+	}
+	return "PubMed"
+}
+
+// maxCustomIDLength is Discord's limit on the custom ID of a component.
+const maxCustomIDLength = 100
+
+const (
+	// componentRefLifetime is how long the buttons of a long identifier
+	// keep working after the last result showing it
+	componentRefLifetime = 90 * 24 * time.Hour
+	// componentRefRefresh is how old a ref gets before showing it again
+	// pushes back its expiry, sparing a write on every result
+	componentRefRefresh = 24 * time.Hour
+)
+
+// errComponentExpired is returned for the buttons of a pruned ref.
+var errComponentExpired = errors.New("component ref expired")
+
+// ComponentIdentifierHelper returns identifier as it goes in a custom ID
+// starting with prefix. Identifiers that would not fit, such as long DOIs,
+// are stored and replaced by "ref:<key>". It is empty when the identifier
+// is empty or can't be stored.
+func ComponentIdentifierHelper(prefix string, identifier string) string {
+	if identifier == "" || len(prefix)+len(identifier) <= maxCustomIDLength {
+		return identifier
+	}
+	key := componentRefKey(identifier)
+	now := time.Now()
+	ref, found, err := componentRefs.Get(key)
+	if err == nil && found && ref.Identifier == identifier && now.Sub(ref.UsedAt) < componentRefRefresh {
+		return "ref:" + key
+	}
+	if err := componentRefs.Put(key, ComponentRef{Identifier: identifier, UsedAt: now}); err != nil {
+		slog.Error("cannot store component identifier", "identifier", identifier, "err", err)
+		return ""
+	}
+	return "ref:" + key
+}
+
+// ResolveComponentIdentifierHelper turns the identifier of a custom ID back
+// into the one ComponentIdentifierHelper was given.
+func ResolveComponentIdentifierHelper(identifier string) (string, error) {
+	key, ok := strings.CutPrefix(identifier, "ref:")
+	if !ok {
+		return identifier, nil
+	}
+	ref, found, err := componentRefs.Get(key)
+	if err != nil {
+		return "", err
+	}
+	if !found {
+		return "", errComponentExpired
+	}
+	return ref.Identifier, nil
+}
+
+// pruneComponentRefsHelper deletes the refs unused for
+// componentRefLifetime.
+func pruneComponentRefsHelper() error {
+	records, err := componentRefs.List("")
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	for _, record := range records {
+		if now.Sub(record.Value.UsedAt) >= componentRefLifetime {
+			if err := componentRefs.Delete(record.Key); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// ComponentResolveErrorHelper answers a button whose identifier can't be
+// resolved.
+func ComponentResolveErrorHelper(
+	ctx context.Context,
+	botSession *discordgo.Session,
+	botInteraction *discordgo.InteractionCreate,
+	err error,
+	message string,
+) {
+	if errors.Is(err, errComponentExpired) {
+		EphemeralResponseHelper(ctx, botSession, botInteraction, "This button has expired, please search for the paper again")
+		return
+	}
+	apihandlers.Logger(ctx).Error("cannot resolve component identifier", "err", err)
+	EphemeralResponseHelper(ctx, botSession, botInteraction, message)
+}
+
+// libraryHelper returns the user's saved papers, oldest first.
+func libraryHelper(userID string) ([]SavedPaper, error) {
+	records, err := savedPapers.List(savedPaperKey(userID, ""))
+	if err != nil {
+		return nil, err
+	}
+	papers := make([]SavedPaper, 0, len(records))
+	for _, record := range records {
+		papers = append(papers, record.Value)
+	}
+	sort.SliceStable(papers, func(i, j int) bool { return papers[i].SavedAt.Before(papers[j].SavedAt) })
+	return papers, nil
+}
+
+// SaveComponentHandler adds the study behind a "Save" button to the user's library.
+func SaveComponentHandler(ctx context.Context, botSession *discordgo.Session, botInteraction *discordgo.InteractionCreate) {
+	// Custom ID is "save:<identifier>", and identifiers contain a colon too
+	identifier, err := ResolveComponentIdentifierHelper(botInteraction.MessageComponentData().CustomID[len("save:"):])
+	if err != nil {
+		ComponentResolveErrorHelper(ctx, botSession, botInteraction, err, "An error happened when saving the paper")
+		return
+	}
+	userID := InteractionUserHelper(botInteraction).ID
+
+	if _, ok, _ := savedPapers.Get(savedPaperKey(userID, identifier)); ok {
+		EphemeralResponseHelper(ctx, botSession, botInteraction, "This paper is already in your library")
+		return
+	}
+
+	study, err := apihandlers.QueryByIdentifier(ctx, identifier)
+	if err != nil {
+		EphemeralResponseHelper(ctx, botSession, botInteraction, ErrorMessageHelper(err, IdentifierSourceHelper(identifier)))
+		return
+	}
+
+	err = savedPapers.Put(savedPaperKey(userID, identifier), SavedPaper{
+		Identifier: identifier,
+		Study:      *study,
+		SavedAt:    time.Now(),
+	})
+	if err != nil {
+		apihandlers.Logger(ctx).Error("cannot save paper", "paper", identifier, "err", err)
+		EphemeralResponseHelper(ctx, botSession, botInteraction, "An error happened when saving the paper")
+		return
+	}
+
+	EphemeralResponseHelper(
+		ctx,
+		botSession,
+		botInteraction,
+		fmt.Sprintf("Saved **%s** to your library, see `/library list`", study.Title),
+	)
+}
+
+func LibraryCommandHandler(ctx context.Context, botSession *discordgo.Session, botInteraction *discordgo.InteractionCreate) {
+	subcommand := botInteraction.ApplicationCommandData().Options[0]
+	optionMap := make(
+		map[string]*discordgo.ApplicationCommandInteractionDataOption,
+		len(subcommand.Options),
+	)
+	for _, opt := range subcommand.Options {
+		optionMap[opt.Name] = opt
+	}
+
+	userID := InteractionUserHelper(botInteraction).ID
+	papers, err := libraryHelper(userID)
+	if err != nil {
+		apihandlers.Logger(ctx).Error("cannot load library", "err", err)
+		EphemeralResponseHelper(ctx, botSession, botInteraction, "An error happened when loading your library")
+		return
+	}
+	if len(papers) == 0 {
+		EphemeralResponseHelper(
+			ctx,
+			botSession,
+			botInteraction,
+			"Your library is empty, use the Save button on a study to add it",
+		)
+		return
+	}
+
+	switch subcommand.Name {
+	case "list":
+		var paperList string
+		for i, paper := range papers {
+			line := fmt.Sprintf("%d. [%s](<%s>) `%s`\n", i+1, paper.Study.Title, paper.Study.Url, paper.Identifier)
+			// Messages are capped at 2000 characters
+			if len(paperList)+len(line) > 1900 {
+				paperList += fmt.Sprintf("… and %d more, use `/library export` for all of them", len(papers)-i)
+				break
+			}
+			paperList += line
+		}
+		EphemeralResponseHelper(ctx, botSession, botInteraction, paperList)
+
+	case "remove":
+		number := int(optionMap["number"].IntValue())
+		if number < 1 || number > len(papers) {
+			EphemeralResponseHelper(
+				ctx,
+				botSession,
+				botInteraction,
+				fmt.Sprintf("Your library has papers 1 to %d", len(papers)),
+			)
+			return
+		}
+		paper := papers[number-1]
+		if err := savedPapers.Delete(savedPaperKey(userID, paper.Identifier)); err != nil {
+			apihandlers.Logger(ctx).Error("cannot remove paper", "paper", paper.Identifier, "err", err)
+			EphemeralResponseHelper(ctx, botSession, botInteraction, "An error happened when removing the paper")
+			return
+		}
+		EphemeralResponseHelper(
+			ctx,
+			botSession,
+			botInteraction,
+			fmt.Sprintf("Removed **%s** from your library", paper.Study.Title),
+		)
+
+	case "export":
+		formatName := "bibtex"
+		if format, ok := optionMap["format"]; ok {
+			formatName = format.StringValue()
+		}
+		studies := make([]apihandlers.StudyStruct, 0, len(papers))
+		for _, paper := range papers {
+			studies = append(studies, paper.Study)
+		}
+		file, err := ExportFileHelper(studies, formatName)
+		if err != nil {
+			apihandlers.Logger(ctx).Error("cannot export library", "err", err)
+			EphemeralResponseHelper(ctx, botSession, botInteraction, "An error happened when exporting your library")
+			return
+		}
+		file.Name = "library." + file.Name[len("studies."):]
+		RespondHelper(ctx, botSession, botInteraction,
+			&discordgo.InteractionResponse{
+				Type: discordgo.InteractionResponseChannelMessageWithSource,
+				Data: &discordgo.InteractionResponseData{
+					Content: fmt.Sprintf("Your library, %d papers", len(papers)),
+					Files:   []*discordgo.File{file},
+					Flags:   1 << 6,
+				},
+			})
+	}
+}
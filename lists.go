@@ -0,0 +1,458 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"scholar-bot/apihandlers"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+var listNameRegex = regexp.MustCompile(`^[a-z0-9_-]{1,32}$`)
+
+var (
+	errListNotFound = errors.New("reading list not found")
+	errListExists   = errors.New("reading list already exists")
+	errPaperQueued  = errors.New("paper already in the list")
+	errPaperNumber  = errors.New("no paper with this number in the list")
+)
+
+// ListPaperHelper finds a paper in the list queue by identifier.
+func ListPaperHelper(list *ReadingList, identifier string) int {
+	for i, paper := range list.Papers {
+		if paper.Identifier == identifier {
+			return i
+		}
+	}
+	return -1
+}
+
+// TopListPaperHelper returns the index of the most voted paper in the
+// queue, the earliest added one winning ties, or -1 when it's empty.
+func TopListPaperHelper(list *ReadingList) int {
+	top := -1
+	for i, paper := range list.Papers {
+		if top == -1 || len(paper.Voters) > len(list.Papers[top].Voters) {
+			top = i
+		}
+	}
+	return top
+}
+
+// toggleListVote adds the user's vote to the paper, or takes it back if
+// they already voted, and returns whether the vote now counts.
+func toggleListVote(paper *ListPaper, userID string) bool {
+	for i, voter := range paper.Voters {
+		if voter == userID {
+			paper.Voters = append(paper.Voters[:i], paper.Voters[i+1:]...)
+			return false
+		}
+	}
+	paper.Voters = append(paper.Voters, userID)
+	return true
+}
+
+func listVoteButtonsHelper(listName string, identifier string) []discordgo.MessageComponent {
+	prefix := fmt.Sprintf("list_vote:%s:", listName)
+	identifier = ComponentIdentifierHelper(prefix, identifier)
+	// Papers of lists with very long names can only be voted on with
+	// /list vote
+	if identifier == "" || len(prefix)+len(identifier) > maxCustomIDLength {
+		return nil
+	}
+	customID := prefix + identifier
+	return []discordgo.MessageComponent{
+		discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+			discordgo.Button{
+				Label:    "Vote",
+				Style:    discordgo.PrimaryButton,
+				CustomID: customID,
+				Emoji:    &discordgo.ComponentEmoji{Name: "👍"},
+			},
+		}},
+	}
+}
+
+// listPaperStudyHelper resolves the paper option of /list add, which is
+// either an identifier or a query whose first PubMed result is used.
+func listPaperStudyHelper(ctx context.Context, paper string) (*apihandlers.StudyStruct, string, error) {
+	identifier, err := apihandlers.ParseIdentifier(paper)
+	if err == nil {
+		study, err := apihandlers.QueryByIdentifier(ctx, identifier)
+		return study, IdentifierSourceHelper(identifier), err
+	}
+	if errors.Is(err, apihandlers.ErrInvalidPmid) {
+		return nil, "PubMed", err
+	}
+	study, err := apihandlers.QueryFirstPMC(ctx, paper, "1800")
+	return study, "PubMed", err
+}
+
+func ListCommandHandler(ctx context.Context, botSession *discordgo.Session, botInteraction *discordgo.InteractionCreate) {
+	subcommand := botInteraction.ApplicationCommandData().Options[0]
+	optionMap := make(
+		map[string]*discordgo.ApplicationCommandInteractionDataOption,
+		len(subcommand.Options),
+	)
+	for _, opt := range subcommand.Options {
+		optionMap[opt.Name] = opt
+	}
+
+	if botInteraction.GuildID == "" {
+		EphemeralResponseHelper(ctx, botSession, botInteraction, "Reading lists only exist in servers")
+		return
+	}
+	listName := strings.ToLower(strings.TrimSpace(optionMap["name"].StringValue()))
+	if !listNameRegex.MatchString(listName) {
+		EphemeralResponseHelper(
+			ctx,
+			botSession,
+			botInteraction,
+			"List names are up to 32 letters, digits, dashes or underscores",
+		)
+		return
+	}
+	listKey := readingListKey(botInteraction.GuildID, listName)
+	userID := InteractionUserHelper(botInteraction).ID
+
+	switch subcommand.Name {
+	case "create":
+		err := readingLists.Update(listKey, func(list *ReadingList, exists bool) (bool, error) {
+			if exists {
+				return false, errListExists
+			}
+			*list = ReadingList{Name: listName, CreatedBy: userID, CreatedAt: time.Now()}
+			return false, nil
+		})
+		if errors.Is(err, errListExists) {
+			EphemeralResponseHelper(ctx, botSession, botInteraction, fmt.Sprintf("The list `%s` already exists", listName))
+			return
+		}
+		if err != nil {
+			apihandlers.Logger(ctx).Error("cannot create list", "list", listKey, "err", err)
+			EphemeralResponseHelper(ctx, botSession, botInteraction, "An error happened when creating the list")
+			return
+		}
+		RespondHelper(ctx, botSession, botInteraction,
+			&discordgo.InteractionResponse{
+				Type: discordgo.InteractionResponseChannelMessageWithSource,
+				Data: &discordgo.InteractionResponseData{
+					Content: fmt.Sprintf("Created the reading list `%s`, add papers with `/list add`", listName),
+				},
+			})
+
+	case "add":
+		study, sourceName, err := listPaperStudyHelper(ctx, optionMap["paper"].StringValue())
+		if err != nil {
+			EphemeralResponseHelper(ctx, botSession, botInteraction, ErrorMessageHelper(err, sourceName))
+			return
+		}
+		ListAddHelper(ctx, botSession, botInteraction, listName, study)
+
+	case "show":
+		list, ok, err := readingLists.Get(listKey)
+		if err != nil || !ok {
+			EphemeralResponseHelper(ctx, botSession, botInteraction, fmt.Sprintf("There is no list `%s`", listName))
+			return
+		}
+		if len(list.Papers) == 0 {
+			EphemeralResponseHelper(ctx, botSession, botInteraction, fmt.Sprintf("The list `%s` is empty", listName))
+			return
+		}
+		paperList := fmt.Sprintf("**%s**\n", listName)
+		for i, paper := range list.Papers {
+			line := fmt.Sprintf("%d. [%s](<%s>) (%d votes)\n", i+1, paper.Study.Title, paper.Study.Url, len(paper.Voters))
+			if len(paperList)+len(line) > 1900 {
+				paperList += fmt.Sprintf("… and %d more", len(list.Papers)-i)
+				break
+			}
+			paperList += line
+		}
+		EphemeralResponseHelper(ctx, botSession, botInteraction, paperList)
+
+	case "vote":
+		number := int(optionMap["number"].IntValue())
+		var voted bool
+		var paper ListPaper
+		var count int
+		err := readingLists.Update(listKey, func(list *ReadingList, exists bool) (bool, error) {
+			if !exists {
+				return false, errListNotFound
+			}
+			count = len(list.Papers)
+			if number < 1 || number > count {
+				return false, errPaperNumber
+			}
+			voted = toggleListVote(&list.Papers[number-1], userID)
+			paper = list.Papers[number-1]
+			return false, nil
+		})
+		switch {
+		case errors.Is(err, errListNotFound):
+			EphemeralResponseHelper(ctx, botSession, botInteraction, fmt.Sprintf("There is no list `%s`", listName))
+			return
+		case errors.Is(err, errPaperNumber) && count == 0:
+			EphemeralResponseHelper(ctx, botSession, botInteraction, fmt.Sprintf("The list `%s` has no papers queued", listName))
+			return
+		case errors.Is(err, errPaperNumber):
+			EphemeralResponseHelper(
+				ctx,
+				botSession,
+				botInteraction,
+				fmt.Sprintf("The list `%s` has papers 1 to %d", listName, count),
+			)
+			return
+		case err != nil:
+			apihandlers.Logger(ctx).Error("cannot vote", "list", listName, "number", number, "err", err)
+			EphemeralResponseHelper(ctx, botSession, botInteraction, "An error happened when voting")
+			return
+		}
+		ListVoteResponseHelper(ctx, botSession, botInteraction, paper, voted)
+
+	case "next":
+		var next ListPaper
+		err := readingLists.Update(listKey, func(list *ReadingList, exists bool) (bool, error) {
+			if !exists {
+				return false, errListNotFound
+			}
+			top := TopListPaperHelper(list)
+			if top == -1 {
+				return false, apihandlers.ErrNoResults
+			}
+			next = list.Papers[top]
+			list.Papers = append(list.Papers[:top], list.Papers[top+1:]...)
+			list.Presented = append(list.Presented, next)
+			return false, nil
+		})
+		switch {
+		case errors.Is(err, errListNotFound):
+			EphemeralResponseHelper(ctx, botSession, botInteraction, fmt.Sprintf("There is no list `%s`", listName))
+			return
+		case errors.Is(err, apihandlers.ErrNoResults):
+			EphemeralResponseHelper(ctx, botSession, botInteraction, fmt.Sprintf("The list `%s` is empty", listName))
+			return
+		case err != nil:
+			apihandlers.Logger(ctx).Error("cannot pick next paper", "list", listKey, "err", err)
+			EphemeralResponseHelper(ctx, botSession, botInteraction, "An error happened when picking the next paper")
+			return
+		}
+		RespondHelper(ctx, botSession, botInteraction,
+			&discordgo.InteractionResponse{
+				Type: discordgo.InteractionResponseChannelMessageWithSource,
+				Data: &discordgo.InteractionResponseData{
+					Content: fmt.Sprintf(
+						"📚 Next up in `%s` with %d votes, added by <@%s>:",
+						listName, len(next.Voters), next.AddedBy,
+					),
+					Embeds: []*discordgo.MessageEmbed{StudyEmbedHelper(&next.Study)},
+				},
+			})
+	}
+}
+
+// ListAddHelper queues study in the guild's list with the user's vote and
+// announces it in the channel.
+func ListAddHelper(
+	ctx context.Context,
+	botSession *discordgo.Session,
+	botInteraction *discordgo.InteractionCreate,
+	listName string,
+	study *apihandlers.StudyStruct,
+) {
+	identifier := study.Identifier()
+	if identifier == "" {
+		EphemeralResponseHelper(ctx, botSession, botInteraction, "This paper has no identifier to queue it by")
+		return
+	}
+	listKey := readingListKey(botInteraction.GuildID, listName)
+	userID := InteractionUserHelper(botInteraction).ID
+	err := readingLists.Update(listKey, func(list *ReadingList, exists bool) (bool, error) {
+		if !exists {
+			return false, errListNotFound
+		}
+		if ListPaperHelper(list, identifier) != -1 {
+			return false, errPaperQueued
+		}
+		list.Papers = append(list.Papers, ListPaper{
+			Identifier: identifier,
+			Study:      *study,
+			AddedBy:    userID,
+			AddedAt:    time.Now(),
+			Voters:     []string{userID},
+		})
+		return false, nil
+	})
+	switch {
+	case errors.Is(err, errListNotFound):
+		EphemeralResponseHelper(ctx, botSession, botInteraction, fmt.Sprintf("There is no list `%s`", listName))
+		return
+	case errors.Is(err, errPaperQueued):
+		EphemeralResponseHelper(ctx, botSession, botInteraction, fmt.Sprintf("This paper is already in `%s`", listName))
+		return
+	case err != nil:
+		apihandlers.Logger(ctx).Error("cannot add to list", "list", listKey, "err", err)
+		EphemeralResponseHelper(ctx, botSession, botInteraction, "An error happened when adding the paper")
+		return
+	}
+	RespondHelper(ctx, botSession, botInteraction,
+		&discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{
+				Content:    fmt.Sprintf("<@%s> added a paper to `%s`, vote for it to read it next", userID, listName),
+				Embeds:     []*discordgo.MessageEmbed{StudyEmbedHelper(study)},
+				Components: listVoteButtonsHelper(listName, identifier),
+			},
+		})
+}
+
+// ListAddComponentHandler answers the "Add to list" button of a result
+// with a menu of the guild's reading lists.
+func ListAddComponentHandler(ctx context.Context, botSession *discordgo.Session, botInteraction *discordgo.InteractionCreate) {
+	// Custom ID is "list_add:<identifier>", passed on to the menu
+	identifier := botInteraction.MessageComponentData().CustomID[len("list_add:"):]
+	if botInteraction.GuildID == "" {
+		EphemeralResponseHelper(ctx, botSession, botInteraction, "Reading lists only exist in servers")
+		return
+	}
+
+	records, err := readingLists.List(readingListKey(botInteraction.GuildID, ""))
+	if err != nil {
+		apihandlers.Logger(ctx).Error("cannot list reading lists", "guild", botInteraction.GuildID, "err", err)
+		EphemeralResponseHelper(ctx, botSession, botInteraction, "An error happened when retrieving the reading lists")
+		return
+	}
+	if len(records) == 0 {
+		EphemeralResponseHelper(ctx, botSession, botInteraction, "This server has no reading list yet, create one with `/list create`")
+		return
+	}
+
+	var options []discordgo.SelectMenuOption
+	for _, record := range records {
+		// Select menus have up to 25 options, the other lists are
+		// reached with /list add
+		if len(options) == 25 {
+			break
+		}
+		options = append(options, discordgo.SelectMenuOption{
+			Label:       record.Value.Name,
+			Value:       record.Value.Name,
+			Description: fmt.Sprintf("%d papers queued", len(record.Value.Papers)),
+		})
+	}
+	RespondHelper(ctx, botSession, botInteraction,
+		&discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{
+				Content: "Which reading list should this paper go to?",
+				Components: []discordgo.MessageComponent{
+					discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+						discordgo.SelectMenu{
+							CustomID:    "list_pick:" + identifier,
+							Placeholder: "Reading list",
+							Options:     options,
+						},
+					}},
+				},
+				Flags: 1 << 6,
+			},
+		})
+}
+
+// ListPickComponentHandler adds the paper to the list picked in the menu
+// of ListAddComponentHandler.
+func ListPickComponentHandler(ctx context.Context, botSession *discordgo.Session, botInteraction *discordgo.InteractionCreate) {
+	// Custom ID is "list_pick:<identifier>", the value is the list name
+	data := botInteraction.MessageComponentData()
+	identifier, err := ResolveComponentIdentifierHelper(data.CustomID[len("list_pick:"):])
+	if err != nil {
+		ComponentResolveErrorHelper(ctx, botSession, botInteraction, err, "An error happened when adding the paper")
+		return
+	}
+	if len(data.Values) != 1 || botInteraction.GuildID == "" {
+		EphemeralResponseHelper(ctx, botSession, botInteraction, "An error happened when adding the paper")
+		return
+	}
+
+	study, err := apihandlers.QueryByIdentifier(ctx, identifier)
+	if err != nil {
+		EphemeralResponseHelper(ctx, botSession, botInteraction, ErrorMessageHelper(err, IdentifierSourceHelper(identifier)))
+		return
+	}
+	ListAddHelper(ctx, botSession, botInteraction, data.Values[0], study)
+}
+
+func ListVoteResponseHelper(
+	ctx context.Context,
+	botSession *discordgo.Session,
+	botInteraction *discordgo.InteractionCreate,
+	paper ListPaper,
+	voted bool,
+) {
+	action := "Removed your vote for"
+	if voted {
+		action = "Voted for"
+	}
+	EphemeralResponseHelper(
+		ctx,
+		botSession,
+		botInteraction,
+		fmt.Sprintf("%s **%s**, it has %d votes", action, paper.Study.Title, len(paper.Voters)),
+	)
+}
+
+// ListVoteComponentHandler toggles the user's vote from a "Vote" button.
+func ListVoteComponentHandler(ctx context.Context, botSession *discordgo.Session, botInteraction *discordgo.InteractionCreate) {
+	// Custom ID is "list_vote:<list name>:<identifier>"
+	args := strings.SplitN(botInteraction.MessageComponentData().CustomID, ":", 3)
+	if len(args) != 3 || botInteraction.GuildID == "" {
+		EphemeralResponseHelper(ctx, botSession, botInteraction, "An error happened when voting")
+		return
+	}
+	listName := args[1]
+	identifier, err := ResolveComponentIdentifierHelper(args[2])
+	if err != nil {
+		ComponentResolveErrorHelper(ctx, botSession, botInteraction, err, "An error happened when voting")
+		return
+	}
+	userID := InteractionUserHelper(botInteraction).ID
+
+	var voted bool
+	var paper ListPaper
+	err = readingLists.Update(
+		readingListKey(botInteraction.GuildID, listName),
+		func(list *ReadingList, exists bool) (bool, error) {
+			if !exists {
+				return false, errListNotFound
+			}
+			i := ListPaperHelper(list, identifier)
+			if i == -1 {
+				return false, apihandlers.ErrNoResults
+			}
+			voted = toggleListVote(&list.Papers[i], userID)
+			paper = list.Papers[i]
+			return false, nil
+		})
+	switch {
+	case errors.Is(err, errListNotFound):
+		EphemeralResponseHelper(ctx, botSession, botInteraction, fmt.Sprintf("There is no list `%s` anymore", listName))
+		return
+	case errors.Is(err, apihandlers.ErrNoResults):
+		EphemeralResponseHelper(
+			ctx,
+			botSession,
+			botInteraction,
+			fmt.Sprintf("This paper is no longer queued in `%s`", listName),
+		)
+		return
+	case err != nil:
+		apihandlers.Logger(ctx).Error("cannot vote", "list", listName, "paper", identifier, "err", err)
+		EphemeralResponseHelper(ctx, botSession, botInteraction, "An error happened when voting")
+		return
+	}
+	ListVoteResponseHelper(ctx, botSession, botInteraction, paper, voted)
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+
+	"scholar-bot/apihandlers"
+	"scholar-bot/config"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// logLevel is the level of the bot's logger, changed by reloads.
+var logLevel = new(slog.LevelVar)
+
+// LoggerHelper builds the bot's logger, writing to w.
+func LoggerHelper(w io.Writer, logConfig config.LogConfig) (*slog.Logger, error) {
+	if err := logLevel.UnmarshalText([]byte(logConfig.Level)); err != nil {
+		return nil, fmt.Errorf("invalid log level %q", logConfig.Level)
+	}
+	options := &slog.HandlerOptions{Level: logLevel}
+
+	switch format := strings.ToLower(logConfig.Format); format {
+	case "", "text":
+		return slog.New(slog.NewTextHandler(w, options)), nil
+	case "json":
+		return slog.New(slog.NewJSONHandler(w, options)), nil
+	default:
+		return nil, fmt.Errorf("invalid log format %q", format)
+	}
+}
+
+// FatalHelper logs msg at error level and exits.
+func FatalHelper(msg string, args ...any) {
+	slog.Error(msg, args...)
+	os.Exit(exitFatal)
+}
+
+// InteractionLoggerHelper returns a context whose logger carries the
+// interaction's ID, guild and user, for correlating its queries.
+func InteractionLoggerHelper(ctx context.Context, botInteraction *discordgo.InteractionCreate) context.Context {
+	logger := slog.With("interaction", botInteraction.ID)
+	if botInteraction.GuildID != "" {
+		logger = logger.With("guild", botInteraction.GuildID)
+	}
+	if user := InteractionUserHelper(botInteraction); user != nil {
+		logger = logger.With("user", user.ID)
+	}
+	return apihandlers.ContextWithLogger(ctx, logger)
+}
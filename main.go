@@ -1,39 +1,227 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
 	"fmt"
-	"log"
+	"log/slog"
+	"net/http"
 	"os"
 	"os/signal"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"time"
 
 	"scholar-bot/apihandlers"
+	"scholar-bot/storage"
 
 	"github.com/bwmarrin/discordgo"
 )
 
 var botSession *discordgo.Session
 
+// botStore persists guild, user and query state across restarts.
+var botStore storage.Store
+
+var (
+	guildSettings storage.Repository[GuildSettings]
+	savedPapers   storage.Repository[SavedPaper]
+	readingLists  storage.Repository[ReadingList]
+	alerts        storage.Repository[Alert]
+	// componentRefs maps the keys of componentRefKey to identifiers
+	componentRefs storage.Repository[ComponentRef]
+)
+
+// scholarSource answers the Google Scholar commands, falling back to the
+// source named in sources.fallback when Scholar blocks us.
+var scholarSource atomic.Pointer[apihandlers.Source]
+
+func ScholarSourceHelper() apihandlers.Source {
+	return *scholarSource.Load()
+}
+
 func init() {
-	var botToken string
 	var err error
-	botToken = os.Getenv("scholar_bot")
-
-	botSession, err = discordgo.New("Bot " + botToken)
+	// The token is set once the configuration is loaded
+	botSession, err = discordgo.New("")
 	if err != nil {
-		log.Fatalf("Invalid bot token: %v", err)
+		FatalHelper("cannot create the session", "err", err)
 	}
 }
 
-func YearInputHelper(optionMap map[string]*discordgo.ApplicationCommandInteractionDataOption) string {
+func YearInputHelper(
+	optionMap map[string]*discordgo.ApplicationCommandInteractionDataOption,
+	settings GuildSettings,
+) string {
 	if minYear, ok := optionMap["minyear"]; ok {
 		return fmt.Sprint(minYear.IntValue())
 	} else {
-		return "2015"
+		return fmt.Sprint(settings.MinYear)
 	}
 }
 
-var (
-	commands = []*discordgo.ApplicationCommand{
+func ErrorMessageHelper(err error, sourceName string) string {
+	switch {
+	case errors.Is(err, apihandlers.ErrNoResults):
+		return fmt.Sprintf("No studies found on %s for this query", sourceName)
+	case errors.Is(err, apihandlers.ErrScholarBlocked):
+		return "Google Scholar is blocking the bot right now (CAPTCHA), please try again later"
+	case errors.Is(err, apihandlers.ErrScholarCoolDown):
+		return fmt.Sprintf(
+			"Google Scholar blocked the bot recently, please try again in %d minutes",
+			int(apihandlers.ScholarCoolDownRemaining().Minutes())+1,
+		)
+	case errors.Is(err, apihandlers.ErrCircuitOpen):
+		return fmt.Sprintf("%s is not responding right now, please try again in a few minutes", sourceName)
+	case errors.Is(err, apihandlers.ErrSourceDisabled):
+		return fmt.Sprintf("%s is disabled on this bot", sourceName)
+	case errors.Is(err, apihandlers.ErrInvalidPmid):
+		return "PMIDs are numbers, like `pmid:33283989`"
+	default:
+		return fmt.Sprintf("An error happened when retrieving the studies from %s", sourceName)
+	}
+}
+
+func FallbackNoticeHelper(requestedSource string, resultSource string) string {
+	if resultSource == "" || resultSource == requestedSource {
+		return ""
+	}
+	return fmt.Sprintf(
+		"%s is unavailable right now, showing results from %s instead\n",
+		apihandlers.Sources[requestedSource].Name(),
+		apihandlers.Sources[resultSource].Name(),
+	)
+}
+
+func StudyEmbedHelper(study *apihandlers.StudyStruct) *discordgo.MessageEmbed {
+	embed := &discordgo.MessageEmbed{
+		Title:       study.Title,
+		Description: study.Abstract,
+		URL:         study.Url,
+		Author: &discordgo.MessageEmbedAuthor{
+			Name: study.Authors,
+		},
+	}
+
+	if study.Venue != "" {
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
+			Name: "Venue", Value: study.Venue, Inline: true,
+		})
+	}
+	if study.Year != "" {
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
+			Name: "Year", Value: study.Year, Inline: true,
+		})
+	}
+	if study.CitedByUrl != "" {
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
+			Name:   "Cited by",
+			Value:  fmt.Sprintf("[%d](%s)", study.CitedBy, study.CitedByUrl),
+			Inline: true,
+		})
+	}
+	if study.VersionsUrl != "" {
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
+			Name:   "Versions",
+			Value:  fmt.Sprintf("[%d](%s)", study.Versions, study.VersionsUrl),
+			Inline: true,
+		})
+	}
+	if study.RelatedUrl != "" {
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
+			Name:   "Related",
+			Value:  fmt.Sprintf("[Related articles](%s)", study.RelatedUrl),
+			Inline: true,
+		})
+	}
+	if study.PdfUrl != "" {
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
+			Name:   "PDF",
+			Value:  fmt.Sprintf("[Full text](%s)", study.PdfUrl),
+			Inline: true,
+		})
+	}
+	if study.Publisher != "" {
+		embed.Footer = &discordgo.MessageEmbedFooter{Text: study.Publisher}
+	}
+
+	return embed
+}
+
+func StudyButtonsHelper(study *apihandlers.StudyStruct) []discordgo.MessageComponent {
+	var buttons []discordgo.MessageComponent
+	if identifier := ComponentIdentifierHelper("save:", study.Identifier()); identifier != "" {
+		buttons = append(buttons, discordgo.Button{
+			Label:    "Save",
+			Style:    discordgo.PrimaryButton,
+			CustomID: "save:" + identifier,
+		})
+	}
+	// The identifier is passed on from the button to the list menu, whose
+	// custom ID prefix is the longer one
+	if identifier := ComponentIdentifierHelper("list_pick:", study.Identifier()); identifier != "" {
+		buttons = append(buttons, discordgo.Button{
+			Label:    "Add to list",
+			Style:    discordgo.SecondaryButton,
+			CustomID: "list_add:" + identifier,
+		})
+	}
+	if study.Source == "scholar" && study.CiteId != "" {
+		buttons = append(buttons, discordgo.Button{
+			Label:    "Cite",
+			Style:    discordgo.SecondaryButton,
+			CustomID: fmt.Sprintf("gs_cite:%s:%s", apihandlers.GsCiteBibTeX, study.CiteId),
+		})
+	}
+	if study.Pmid != "" {
+		buttons = append(buttons, discordgo.Button{
+			Label:    "Export",
+			Style:    discordgo.SecondaryButton,
+			CustomID: fmt.Sprintf("export:bibtex:%s", study.Pmid),
+		})
+	}
+	if len(buttons) == 0 {
+		return nil
+	}
+	return []discordgo.MessageComponent{discordgo.ActionsRow{Components: buttons}}
+}
+
+func StudyListHelper(studies []apihandlers.StudyStruct) string {
+	var studyTextList string
+	for _, studyStruct := range studies {
+		var details []string
+		if studyStruct.Year != "" {
+			details = append(details, studyStruct.Year)
+		}
+		if studyStruct.CitedByUrl != "" {
+			details = append(details, fmt.Sprintf("cited by %d", studyStruct.CitedBy))
+		}
+		if studyStruct.PdfUrl != "" {
+			details = append(details, fmt.Sprintf("[PDF](<%s>)", studyStruct.PdfUrl))
+		}
+
+		studyTextList = studyTextList + fmt.Sprintf(
+			"- [%s](<%s>)",
+			studyStruct.Title,
+			studyStruct.Url,
+		)
+		if len(details) != 0 {
+			studyTextList = studyTextList + " (" + strings.Join(details, ", ") + ")"
+		}
+		studyTextList = studyTextList + "\n"
+	}
+	return studyTextList
+}
+
+var manageServerPermission int64 = discordgo.PermissionManageServer
+
+// CommandsHelper returns the definitions of the bot's commands. Source
+// options only offer the enabled sources, so the definitions can change
+// when the configuration is reloaded.
+func CommandsHelper() []*discordgo.ApplicationCommand {
+	return []*discordgo.ApplicationCommand{
 		{
 			Name:        "gs",
 			Description: "Get first study found on google scholar",
@@ -47,7 +235,7 @@ var (
 				{
 					Type:        discordgo.ApplicationCommandOptionInteger,
 					Name:        "minyear",
-					Description: "Minimum year for study (default 2015)",
+					Description: "Minimum year for study (defaults to the server's setting)",
 					Required:    false,
 				},
 			},
@@ -65,7 +253,7 @@ var (
 				{
 					Type:        discordgo.ApplicationCommandOptionInteger,
 					Name:        "minyear",
-					Description: "Minimum year for study (default 2015)",
+					Description: "Minimum year for study (defaults to the server's setting)",
 					Required:    false,
 				},
 			},
@@ -83,7 +271,7 @@ var (
 				{
 					Type:        discordgo.ApplicationCommandOptionInteger,
 					Name:        "minyear",
-					Description: "Minimum year for study (default 2015)",
+					Description: "Minimum year for study (defaults to the server's setting)",
 					Required:    false,
 				},
 			},
@@ -101,15 +289,513 @@ var (
 				{
 					Type:        discordgo.ApplicationCommandOptionInteger,
 					Name:        "minyear",
-					Description: "Minimum year for study (default 2015)",
+					Description: "Minimum year for study (defaults to the server's setting)",
+					Required:    false,
+				},
+			},
+		},
+		{
+			Name:        "author",
+			Description: "Get a researcher's Google Scholar profile",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "name",
+					Description: "Name of the researcher",
+					Required:    true,
+				},
+			},
+		},
+		{
+			Name:        "export",
+			Description: "Export studies as a citation file for a reference manager",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "google",
+					Description: "What studies should the bot export",
+					Required:    true,
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "format",
+					Description: "File format (default BibTeX)",
+					Required:    false,
+					Choices:     ExportFormatChoicesHelper(),
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "source",
+					Description: "Where to search (default PubMed)",
+					Required:    false,
+					Choices:     SourceChoicesHelper(),
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionBoolean,
+					Name:        "topten",
+					Description: "Export the top 10 studies instead of the first one",
+					Required:    false,
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionInteger,
+					Name:        "minyear",
+					Description: "Minimum year for study (defaults to the server's setting)",
+					Required:    false,
+				},
+			},
+		},
+		{
+			Name:        "cite",
+			Description: "Get a formatted citation for the first study found",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "google",
+					Description: "What study should the bot cite",
+					Required:    true,
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "style",
+					Description: "Citation style (default is the server's, or Vancouver for PubMed)",
+					Required:    false,
+					Choices:     CitationStyleChoicesHelper(),
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "source",
+					Description: "Where to search (default PubMed)",
+					Required:    false,
+					Choices:     SourceChoicesHelper(),
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionInteger,
+					Name:        "minyear",
+					Description: "Minimum year for study (defaults to the server's setting)",
 					Required:    false,
 				},
 			},
 		},
+		{
+			Name:        "library",
+			Description: "Manage your saved papers",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Name:        "list",
+					Description: "List your saved papers",
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Name:        "remove",
+					Description: "Remove a paper from your library",
+					Options: []*discordgo.ApplicationCommandOption{
+						{
+							Type:        discordgo.ApplicationCommandOptionInteger,
+							Name:        "number",
+							Description: "Number of the paper in /library list",
+							Required:    true,
+							MinValue:    &minLibraryNumber,
+						},
+					},
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Name:        "export",
+					Description: "Export your library as a citation file",
+					Options: []*discordgo.ApplicationCommandOption{
+						{
+							Type:        discordgo.ApplicationCommandOptionString,
+							Name:        "format",
+							Description: "File format (default BibTeX)",
+							Required:    false,
+							Choices:     ExportFormatChoicesHelper(),
+						},
+					},
+				},
+			},
+		},
+		{
+			Name:        "list",
+			Description: "Manage the server's journal club reading lists",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Name:        "create",
+					Description: "Create a reading list",
+					Options: []*discordgo.ApplicationCommandOption{
+						{
+							Type:        discordgo.ApplicationCommandOptionString,
+							Name:        "name",
+							Description: "Name of the reading list",
+							Required:    true,
+						},
+					},
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Name:        "add",
+					Description: "Add a paper to a reading list",
+					Options: []*discordgo.ApplicationCommandOption{
+						{
+							Type:        discordgo.ApplicationCommandOptionString,
+							Name:        "name",
+							Description: "Name of the reading list",
+							Required:    true,
+						},
+						{
+							Type:        discordgo.ApplicationCommandOptionString,
+							Name:        "paper",
+							Description: "PMID, DOI, or a query whose first PubMed result is added",
+							Required:    true,
+						},
+					},
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Name:        "show",
+					Description: "Show the papers queued in a reading list",
+					Options: []*discordgo.ApplicationCommandOption{
+						{
+							Type:        discordgo.ApplicationCommandOptionString,
+							Name:        "name",
+							Description: "Name of the reading list",
+							Required:    true,
+						},
+					},
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Name:        "vote",
+					Description: "Vote for a queued paper, or take your vote back",
+					Options: []*discordgo.ApplicationCommandOption{
+						{
+							Type:        discordgo.ApplicationCommandOptionString,
+							Name:        "name",
+							Description: "Name of the reading list",
+							Required:    true,
+						},
+						{
+							Type:        discordgo.ApplicationCommandOptionInteger,
+							Name:        "number",
+							Description: "Number of the paper in /list show",
+							Required:    true,
+							MinValue:    &minLibraryNumber,
+						},
+					},
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Name:        "next",
+					Description: "Announce the most voted paper for the next session",
+					Options: []*discordgo.ApplicationCommandOption{
+						{
+							Type:        discordgo.ApplicationCommandOptionString,
+							Name:        "name",
+							Description: "Name of the reading list",
+							Required:    true,
+						},
+					},
+				},
+			},
+		},
+		{
+			Name:                     "alert",
+			Description:              "Post new papers matching a search to a channel",
+			DefaultMemberPermissions: &manageServerPermission,
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Name:        "create",
+					Description: "Create an alert for a search",
+					Options: []*discordgo.ApplicationCommandOption{
+						{
+							Type:        discordgo.ApplicationCommandOptionString,
+							Name:        "query",
+							Description: "What the alert should search for",
+							Required:    true,
+						},
+						{
+							Type:        discordgo.ApplicationCommandOptionString,
+							Name:        "source",
+							Description: "Where to search (default PubMed)",
+							Required:    false,
+							Choices:     SourceChoicesHelper(),
+						},
+						{
+							Type:         discordgo.ApplicationCommandOptionChannel,
+							Name:         "channel",
+							Description:  "Channel to post new papers in (default this one)",
+							Required:     false,
+							ChannelTypes: []discordgo.ChannelType{discordgo.ChannelTypeGuildText},
+						},
+						{
+							Type:        discordgo.ApplicationCommandOptionInteger,
+							Name:        "interval",
+							Description: "How often to search (default daily)",
+							Required:    false,
+							Choices:     alertIntervalChoices,
+						},
+					},
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Name:        "list",
+					Description: "List the server's alerts",
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Name:        "feed",
+					Description: "Get the Atom feed URL of an alert",
+					Options: []*discordgo.ApplicationCommandOption{
+						{
+							Type:        discordgo.ApplicationCommandOptionString,
+							Name:        "id",
+							Description: "ID of the alert, see /alert list",
+							Required:    true,
+						},
+					},
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Name:        "pause",
+					Description: "Pause an alert",
+					Options: []*discordgo.ApplicationCommandOption{
+						{
+							Type:        discordgo.ApplicationCommandOptionString,
+							Name:        "id",
+							Description: "ID of the alert, see /alert list",
+							Required:    true,
+						},
+					},
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Name:        "resume",
+					Description: "Resume a paused alert",
+					Options: []*discordgo.ApplicationCommandOption{
+						{
+							Type:        discordgo.ApplicationCommandOptionString,
+							Name:        "id",
+							Description: "ID of the alert, see /alert list",
+							Required:    true,
+						},
+					},
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Name:        "delete",
+					Description: "Delete an alert",
+					Options: []*discordgo.ApplicationCommandOption{
+						{
+							Type:        discordgo.ApplicationCommandOptionString,
+							Name:        "id",
+							Description: "ID of the alert, see /alert list",
+							Required:    true,
+						},
+					},
+				},
+			},
+		},
+		{
+			Name:                     "digest",
+			Description:              "Batch the server's alerts into a weekly digest",
+			DefaultMemberPermissions: &manageServerPermission,
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Name:        "enable",
+					Description: "Post new alert papers once a week instead of as they are found",
+					Options: []*discordgo.ApplicationCommandOption{
+						{
+							Type:         discordgo.ApplicationCommandOptionChannel,
+							Name:         "channel",
+							Description:  "Channel to post the digest in (default this one)",
+							Required:     false,
+							ChannelTypes: []discordgo.ChannelType{discordgo.ChannelTypeGuildText},
+						},
+						{
+							Type:        discordgo.ApplicationCommandOptionInteger,
+							Name:        "day",
+							Description: "Day of the week (default Monday)",
+							Required:    false,
+							Choices:     digestWeekdayChoices,
+						},
+						{
+							Type:        discordgo.ApplicationCommandOptionString,
+							Name:        "time",
+							Description: "Time of day as HH:MM (default 09:00)",
+							Required:    false,
+						},
+						{
+							Type:        discordgo.ApplicationCommandOptionString,
+							Name:        "timezone",
+							Description: "Timezone such as Europe/Paris (default UTC)",
+							Required:    false,
+						},
+					},
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Name:        "disable",
+					Description: "Go back to posting alert papers as they are found",
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Name:        "status",
+					Description: "Show the digest schedule",
+				},
+			},
+		},
+		{
+			Name:                     "status",
+			Description:              "Show whether the search sources are up",
+			DefaultMemberPermissions: &manageServerPermission,
+		},
+		{
+			Name:                     "admin",
+			Description:              "Manage the bot (bot owner only)",
+			DefaultMemberPermissions: &manageServerPermission,
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Name:        "reload",
+					Description: "Reload the configuration",
+				},
+			},
+		},
+		{
+			Name:                     "config",
+			Description:              "Configure the bot for this server",
+			DefaultMemberPermissions: &manageServerPermission,
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Name:        "show",
+					Description: "Show the server's configuration",
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Name:        "source",
+					Description: "Set the default source of /cite, /export and /alert",
+					Options: []*discordgo.ApplicationCommandOption{
+						{
+							Type:        discordgo.ApplicationCommandOptionString,
+							Name:        "source",
+							Description: "Default source",
+							Required:    true,
+							Choices:     SourceChoicesHelper(),
+						},
+					},
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Name:        "minyear",
+					Description: "Set the default minimum year of searches",
+					Options: []*discordgo.ApplicationCommandOption{
+						{
+							Type:        discordgo.ApplicationCommandOptionInteger,
+							Name:        "minyear",
+							Description: "Default minimum year",
+							Required:    true,
+							MinValue:    &minMinYear,
+							MaxValue:    maxMinYearHelper(),
+						},
+					},
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Name:        "results",
+					Description: "Set how many results the top 10 commands show",
+					Options: []*discordgo.ApplicationCommandOption{
+						{
+							Type:        discordgo.ApplicationCommandOptionInteger,
+							Name:        "count",
+							Description: "Results per page",
+							Required:    true,
+							MinValue:    &minResultsPerPage,
+							MaxValue:    maxResultsPerPage,
+						},
+					},
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Name:        "style",
+					Description: "Set the default citation style",
+					Options: []*discordgo.ApplicationCommandOption{
+						{
+							Type:        discordgo.ApplicationCommandOptionString,
+							Name:        "style",
+							Description: "Citation style",
+							Required:    true,
+							Choices:     CitationStyleChoicesHelper(),
+						},
+					},
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Name:        "replies",
+					Description: "Choose whether search results are public or only visible to the user",
+					Options: []*discordgo.ApplicationCommandOption{
+						{
+							Type:        discordgo.ApplicationCommandOptionBoolean,
+							Name:        "private",
+							Description: "Only show results to the user who searched",
+							Required:    true,
+						},
+					},
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Name:        "command",
+					Description: "Turn a command on or off in this server",
+					Options: []*discordgo.ApplicationCommandOption{
+						{
+							Type:        discordgo.ApplicationCommandOptionString,
+							Name:        "command",
+							Description: "Name of the command, such as gst10",
+							Required:    true,
+						},
+						{
+							Type:        discordgo.ApplicationCommandOptionBoolean,
+							Name:        "enabled",
+							Description: "Whether the command can be used",
+							Required:    true,
+						},
+					},
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Name:        "exempt",
+					Description: "Exempt a role from the bot's rate limits",
+					Options: []*discordgo.ApplicationCommandOption{
+						{
+							Type:        discordgo.ApplicationCommandOptionRole,
+							Name:        "role",
+							Description: "Role",
+							Required:    true,
+						},
+						{
+							Type:        discordgo.ApplicationCommandOptionBoolean,
+							Name:        "exempt",
+							Description: "Whether members with the role skip rate limits",
+							Required:    true,
+						},
+					},
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Name:        "reset",
+					Description: "Go back to the bot's defaults",
+				},
+			},
+		},
 	}
+}
 
-	commandHandlers = map[string]func(botSession *discordgo.Session, botInteraction *discordgo.InteractionCreate){
-		"gs": func(botSession *discordgo.Session, botInteraction *discordgo.InteractionCreate) {
+var (
+	commandHandlers = map[string]func(ctx context.Context, botSession *discordgo.Session, botInteraction *discordgo.InteractionCreate){
+		"gs": func(ctx context.Context, botSession *discordgo.Session, botInteraction *discordgo.InteractionCreate) {
 			options := botInteraction.ApplicationCommandData().Options
 			optionMap := make(
 				map[string]*discordgo.ApplicationCommandInteractionDataOption,
@@ -118,42 +804,36 @@ var (
 			for _, opt := range options {
 				optionMap[opt.Name] = opt
 			}
+			settings := GuildSettingsHelper(botInteraction.GuildID)
 
 			if query, ok := optionMap["google"]; ok {
 				var studyEmbed *apihandlers.StudyStruct
-				studyEmbed, ok := apihandlers.QueryFirstGs(query.StringValue(),YearInputHelper(optionMap))
-				if ok {
-					botSession.InteractionRespond(
-						botInteraction.Interaction,
+				studyEmbed, err := ScholarSourceHelper().QueryFirst(ctx, query.StringValue(), YearInputHelper(optionMap, settings))
+				if err == nil {
+					RespondHelper(ctx, botSession, botInteraction,
 						&discordgo.InteractionResponse{
 							Type: discordgo.InteractionResponseChannelMessageWithSource,
 							Data: &discordgo.InteractionResponseData{
+								Content: FallbackNoticeHelper("scholar", studyEmbed.Source),
 								Embeds: []*discordgo.MessageEmbed{
-									{
-										Title:       studyEmbed.Title,
-										Description: studyEmbed.Abstract,
-										URL:         studyEmbed.Url,
-										Author: &discordgo.MessageEmbedAuthor{
-											Name: studyEmbed.Authors,
-										},
-									},
+									StudyEmbedHelper(studyEmbed),
 								},
+								Components: StudyButtonsHelper(studyEmbed),
+								Flags:      ResultFlagsHelper(settings),
 							},
 						})
 				} else {
-					botSession.InteractionRespond(
-						botInteraction.Interaction,
+					RespondHelper(ctx, botSession, botInteraction,
 						&discordgo.InteractionResponse{
 							Type: discordgo.InteractionResponseChannelMessageWithSource,
 							Data: &discordgo.InteractionResponseData{
-								Content: "An error happened when retrieving the studies from google scholar",
+								Content: ErrorMessageHelper(err, "Google Scholar"),
 								Flags:   1 << 6,
 							},
 						})
 				}
 			} else {
-				botSession.InteractionRespond(
-					botInteraction.Interaction,
+				RespondHelper(ctx, botSession, botInteraction,
 					&discordgo.InteractionResponse{
 						Type: discordgo.InteractionResponseChannelMessageWithSource,
 						Data: &discordgo.InteractionResponseData{
@@ -164,7 +844,7 @@ var (
 			}
 
 		},
-		"gst10": func(botSession *discordgo.Session, botInteraction *discordgo.InteractionCreate) {
+		"gst10": func(ctx context.Context, botSession *discordgo.Session, botInteraction *discordgo.InteractionCreate) {
 			options := botInteraction.ApplicationCommandData().Options
 			optionMap := make(
 				map[string]*discordgo.ApplicationCommandInteractionDataOption,
@@ -173,42 +853,35 @@ var (
 			for _, opt := range options {
 				optionMap[opt.Name] = opt
 			}
+			settings := GuildSettingsHelper(botInteraction.GuildID)
 
 			if query, ok := optionMap["google"]; ok {
 				var studySlice *[]apihandlers.StudyStruct
-				studySlice, ok := apihandlers.QueryTopTenGs(query.StringValue(), YearInputHelper(optionMap))
-				var studyTextList string
-				for _, studyStruct := range *studySlice {
-					studyTextList = studyTextList + fmt.Sprintf(
-						"- [%s](<%s>)\n",
-						studyStruct.Title,
-						studyStruct.Url,
-					)
-				}
-				if ok {
-					botSession.InteractionRespond(
-						botInteraction.Interaction,
+				studySlice, err := ScholarSourceHelper().QueryTopTen(ctx, query.StringValue(), YearInputHelper(optionMap, settings))
+				if err == nil {
+					studyTextList := FallbackNoticeHelper("scholar", (*studySlice)[0].Source) +
+						StudyListHelper(ResultsPageHelper(*studySlice, settings))
+					RespondHelper(ctx, botSession, botInteraction,
 						&discordgo.InteractionResponse{
 							Type: discordgo.InteractionResponseChannelMessageWithSource,
 							Data: &discordgo.InteractionResponseData{
 								Content: studyTextList,
 								Embeds:  nil,
+								Flags:   ResultFlagsHelper(settings),
 							},
 						})
 				} else {
-					botSession.InteractionRespond(
-						botInteraction.Interaction,
+					RespondHelper(ctx, botSession, botInteraction,
 						&discordgo.InteractionResponse{
 							Type: discordgo.InteractionResponseChannelMessageWithSource,
 							Data: &discordgo.InteractionResponseData{
-								Content: "An error happened when retrieving the studies from google scholar",
+								Content: ErrorMessageHelper(err, "Google Scholar"),
 								Flags:   1 << 6,
 							},
 						})
 				}
 			} else {
-				botSession.InteractionRespond(
-					botInteraction.Interaction,
+				RespondHelper(ctx, botSession, botInteraction,
 					&discordgo.InteractionResponse{
 						Type: discordgo.InteractionResponseChannelMessageWithSource,
 						Data: &discordgo.InteractionResponseData{
@@ -219,7 +892,7 @@ var (
 			}
 
 		},
-		"pmc": func(botSession *discordgo.Session, botInteraction *discordgo.InteractionCreate) {
+		"pmc": func(ctx context.Context, botSession *discordgo.Session, botInteraction *discordgo.InteractionCreate) {
 			options := botInteraction.ApplicationCommandData().Options
 			optionMap := make(
 				map[string]*discordgo.ApplicationCommandInteractionDataOption,
@@ -228,42 +901,35 @@ var (
 			for _, opt := range options {
 				optionMap[opt.Name] = opt
 			}
+			settings := GuildSettingsHelper(botInteraction.GuildID)
 
 			if query, ok := optionMap["google"]; ok {
 				var studyEmbed *apihandlers.StudyStruct
-				studyEmbed, ok := apihandlers.QueryFirstPMC(query.StringValue(), YearInputHelper(optionMap))
-				if ok {
-					botSession.InteractionRespond(
-						botInteraction.Interaction,
+				studyEmbed, err := apihandlers.Sources["pubmed"].QueryFirst(ctx, query.StringValue(), YearInputHelper(optionMap, settings))
+				if err == nil {
+					RespondHelper(ctx, botSession, botInteraction,
 						&discordgo.InteractionResponse{
 							Type: discordgo.InteractionResponseChannelMessageWithSource,
 							Data: &discordgo.InteractionResponseData{
 								Embeds: []*discordgo.MessageEmbed{
-									{
-										Title:       studyEmbed.Title,
-										Description: studyEmbed.Abstract,
-										URL:         studyEmbed.Url,
-										Author: &discordgo.MessageEmbedAuthor{
-											Name: studyEmbed.Authors,
-										},
-									},
+									StudyEmbedHelper(studyEmbed),
 								},
+								Components: StudyButtonsHelper(studyEmbed),
+								Flags:      ResultFlagsHelper(settings),
 							},
 						})
 				} else {
-					botSession.InteractionRespond(
-						botInteraction.Interaction,
+					RespondHelper(ctx, botSession, botInteraction,
 						&discordgo.InteractionResponse{
 							Type: discordgo.InteractionResponseChannelMessageWithSource,
 							Data: &discordgo.InteractionResponseData{
-								Content: "An error happened when retrieving the studies from PubMed",
+								Content: ErrorMessageHelper(err, "PubMed"),
 								Flags:   1 << 6,
 							},
 						})
 				}
 			} else {
-				botSession.InteractionRespond(
-					botInteraction.Interaction,
+				RespondHelper(ctx, botSession, botInteraction,
 					&discordgo.InteractionResponse{
 						Type: discordgo.InteractionResponseChannelMessageWithSource,
 						Data: &discordgo.InteractionResponseData{
@@ -274,7 +940,7 @@ var (
 			}
 
 		},
-		"pmct10": func(botSession *discordgo.Session, botInteraction *discordgo.InteractionCreate) {
+		"pmct10": func(ctx context.Context, botSession *discordgo.Session, botInteraction *discordgo.InteractionCreate) {
 			options := botInteraction.ApplicationCommandData().Options
 			optionMap := make(
 				map[string]*discordgo.ApplicationCommandInteractionDataOption,
@@ -283,42 +949,34 @@ var (
 			for _, opt := range options {
 				optionMap[opt.Name] = opt
 			}
+			settings := GuildSettingsHelper(botInteraction.GuildID)
 
 			if query, ok := optionMap["google"]; ok {
 				var studySlice *[]apihandlers.StudyStruct
-				studySlice, ok := apihandlers.QueryTopTenPMC(query.StringValue(), YearInputHelper(optionMap))
-				var studyTextList string
-				for _, studyStruct := range *studySlice {
-					studyTextList = studyTextList + fmt.Sprintf(
-						"- [%s](<%s>)\n",
-						studyStruct.Title,
-						studyStruct.Url,
-					)
-				}
-				if ok {
-					botSession.InteractionRespond(
-						botInteraction.Interaction,
+				studySlice, err := apihandlers.Sources["pubmed"].QueryTopTen(ctx, query.StringValue(), YearInputHelper(optionMap, settings))
+				if err == nil {
+					studyTextList := StudyListHelper(ResultsPageHelper(*studySlice, settings))
+					RespondHelper(ctx, botSession, botInteraction,
 						&discordgo.InteractionResponse{
 							Type: discordgo.InteractionResponseChannelMessageWithSource,
 							Data: &discordgo.InteractionResponseData{
 								Content: studyTextList,
 								Embeds:  nil,
+								Flags:   ResultFlagsHelper(settings),
 							},
 						})
 				} else {
-					botSession.InteractionRespond(
-						botInteraction.Interaction,
+					RespondHelper(ctx, botSession, botInteraction,
 						&discordgo.InteractionResponse{
 							Type: discordgo.InteractionResponseChannelMessageWithSource,
 							Data: &discordgo.InteractionResponseData{
-								Content: "An error happened when retrieving the studies from google scholar",
+								Content: ErrorMessageHelper(err, "PubMed"),
 								Flags:   1 << 6,
 							},
 						})
 				}
 			} else {
-				botSession.InteractionRespond(
-					botInteraction.Interaction,
+				RespondHelper(ctx, botSession, botInteraction,
 					&discordgo.InteractionResponse{
 						Type: discordgo.InteractionResponseChannelMessageWithSource,
 						Data: &discordgo.InteractionResponseData{
@@ -329,64 +987,171 @@ var (
 			}
 
 		},
+		"author":  AuthorCommandHandler,
+		"export":  ExportCommandHandler,
+		"cite":    CiteCommandHandler,
+		"library": LibraryCommandHandler,
+		"list":    ListCommandHandler,
+		"alert":   AlertCommandHandler,
+		"digest":  DigestCommandHandler,
+		"config":  ConfigCommandHandler,
+		"status":  StatusCommandHandler,
+		"admin":   AdminCommandHandler,
+	}
+
+	componentHandlers = map[string]func(ctx context.Context, botSession *discordgo.Session, botInteraction *discordgo.InteractionCreate){
+		"gs_cite":   GsCiteComponentHandler,
+		"export":    ExportComponentHandler,
+		"save":      SaveComponentHandler,
+		"list_vote": ListVoteComponentHandler,
+		"list_add":  ListAddComponentHandler,
+		"list_pick": ListPickComponentHandler,
+	}
+
+	// componentCommands names the command each component belongs to, so a
+	// guild disabling a command also disables its buttons
+	componentCommands = map[string]string{
+		"gs_cite":   "cite",
+		"export":    "export",
+		"save":      "library",
+		"list_vote": "list",
+		"list_add":  "list",
+		"list_pick": "list",
 	}
 )
 
 func init() {
 	botSession.AddHandler(func(s *discordgo.Session, i *discordgo.InteractionCreate) {
-		if h, ok := commandHandlers[i.ApplicationCommandData().Name]; ok {
-			h(s, i)
+		done, ok := StartWorkHelper()
+		if !ok {
+			EphemeralResponseHelper(context.Background(), s, i, "The bot is restarting, please try again in a minute")
+			return
+		}
+		defer done()
+
+		ctx, cancel := InteractionContextHelper(workCtx, s, i)
+		defer cancel()
+		start := time.Now()
+		switch i.Type {
+		case discordgo.InteractionApplicationCommand:
+			name := i.ApplicationCommandData().Name
+			if CommandDisabledHelper(i.GuildID, name) {
+				EphemeralResponseHelper(ctx, s, i, fmt.Sprintf("The /%s command is disabled on this server", name))
+				return
+			}
+			if delay := RateLimitHelper(i, name); delay > 0 {
+				EphemeralResponseHelper(ctx, s, i, RateLimitMessageHelper(delay))
+				return
+			}
+			if h, ok := commandHandlers[name]; ok {
+				interactionsTotal.Inc("command", name)
+				h(ctx, s, i)
+				apihandlers.Logger(ctx).Info("command", "command", name, "latency", time.Since(start))
+			}
+		case discordgo.InteractionMessageComponent:
+			// Component custom IDs look like "handler:arg1:arg2"
+			name, _, _ := strings.Cut(i.MessageComponentData().CustomID, ":")
+			if command, ok := componentCommands[name]; ok && CommandDisabledHelper(i.GuildID, command) {
+				EphemeralResponseHelper(ctx, s, i, fmt.Sprintf("The /%s command is disabled on this server", command))
+				return
+			}
+			if delay := RateLimitHelper(i, name); delay > 0 {
+				EphemeralResponseHelper(ctx, s, i, RateLimitMessageHelper(delay))
+				return
+			}
+			if h, ok := componentHandlers[name]; ok {
+				interactionsTotal.Inc("component", name)
+				h(ctx, s, i)
+				apihandlers.Logger(ctx).Info("component", "component", name, "latency", time.Since(start))
+			}
 		}
 	})
 }
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "search" {
+		os.Exit(SearchCLIHelper(os.Args[2:], os.Stdout, os.Stderr))
+	}
+
+	botArgs = os.Args[1:]
+	cfg, options, err := LoadConfigHelper(botArgs)
+	switch {
+	case errors.Is(err, flag.ErrHelp):
+		return
+	case err != nil:
+		fmt.Fprintf(os.Stderr, "invalid configuration:\n%v\n", err)
+		os.Exit(exitConfig)
+	case options.PrintConfig:
+		if err := cfg.Write(os.Stdout, false); err != nil {
+			FatalHelper("cannot print the configuration", "err", err)
+		}
+		return
+	}
+	if err := SetupHelper(cfg); err != nil {
+		FatalHelper("cannot set up the bot", "err", err)
+	}
+	if options.Path != "" {
+		slog.Info("configuration loaded", "path", options.Path)
+	}
+
+	SetupMetricsHelper(botSession)
 	botSession.AddHandler(func(botSession *discordgo.Session, botReady *discordgo.Ready) {
-		log.Printf(
-			"Logged in as: %v#%v",
-			botSession.State.User.Username,
-			botSession.State.User.Discriminator,
+		slog.Info(
+			"logged in",
+			"user", botSession.State.User.Username,
+			"discriminator", botSession.State.User.Discriminator,
 		)
 	})
-	err := botSession.Open()
+	err = botSession.Open()
 	if err != nil {
-		log.Fatalf("Cannot open the session: %v", err)
+		FatalHelper("cannot open the session", "err", err)
 	}
 
-	log.Printf("Adding %d commands...\n", len(commands))
-	registeredCommands := make([]*discordgo.ApplicationCommand, len(commands))
-	for i, v := range commands {
-		cmd, err := botSession.ApplicationCommandCreate(
-			botSession.State.User.ID,
-			"",
-			v,
-		)
-		log.Printf("Created %v command and registered", v.Name)
-		if err != nil {
-			log.Panicf("Cannot create '%v' command: %v", v.Name, err)
-		}
-		registeredCommands[i] = cmd
+	if err := SyncCommandsHelper(botSession, cfg.Discord.Guild, CommandsHelper()); err != nil {
+		FatalHelper("cannot sync commands", "err", err)
+	}
+	if err := RemoveGlobalCommandsHelper(botSession, cfg.Discord.Guild); err != nil {
+		slog.Warn("cannot remove global commands", "err", err)
 	}
 
-	defer botSession.Close()
+	schedulerDone, _ := StartWorkHelper()
+	go func() {
+		defer schedulerDone()
+		RunAlertScheduler(workCtx, botSession, stopping)
+	}()
 
-	stop := make(chan os.Signal, 1)
-	signal.Notify(stop, os.Interrupt)
-	log.Println("Press Ctrl+C to exit")
-	<-stop
+	var feedServer *http.Server
+	if feedAddr := cfg.HTTP.FeedAddr; feedAddr != "" {
+		feedBaseUrl = cfg.HTTP.FeedURL
+		if feedBaseUrl == "" {
+			feedBaseUrl = "http://" + feedAddr
+		}
+		feedBaseUrl = strings.TrimSuffix(feedBaseUrl, "/")
+		feedServer = StartFeedServer(feedAddr)
+	}
 
-	log.Println("Removing commands...")
+	var metricsServer *http.Server
+	if metricsAddr := cfg.HTTP.MetricsAddr; metricsAddr != "" {
+		metricsServer = StartMetricsServer(metricsAddr)
+	}
 
-	for _, v := range registeredCommands {
-		err := botSession.ApplicationCommandDelete(
-			botSession.State.User.ID,
-			"",
-			v.ID,
-		)
-		if err != nil {
-			log.Panicf("Cannot delete '%v' command: %v", v.Name, err)
-		}
+	var apiServer *http.Server
+	if apiAddr := cfg.HTTP.APIAddr; apiAddr != "" {
+		apiServer = StartAPIServer(apiAddr)
 	}
 
-	log.Println("Gracefully shutting down.")
+	reload := make(chan os.Signal, 1)
+	signal.Notify(reload, syscall.SIGHUP)
+	go func() {
+		for range reload {
+			if _, err := ReloadConfigHelper(botSession); err != nil {
+				slog.Error("cannot reload configuration", "err", err)
+			}
+		}
+	}()
+
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+	slog.Info("running, press Ctrl+C to exit")
+	os.Exit(ShutdownHelper(stop, apiServer, metricsServer, feedServer))
 }
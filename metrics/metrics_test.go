@@ -0,0 +1,122 @@
+package metrics
+
+import (
+	"math"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// unregister removes the named metrics once the test ends, so tests can be
+// run again in the same process.
+func unregister(t *testing.T, names ...string) {
+	t.Cleanup(func() {
+		registryMu.Lock()
+		defer registryMu.Unlock()
+		for _, name := range names {
+			delete(registry, name)
+		}
+	})
+}
+
+func TestCounterText(t *testing.T) {
+	unregister(t, "test_counter_total")
+	counter := NewCounter("test_counter_total", "A counter.", "source", "result")
+	counter.Inc("pubmed", "hit")
+	counter.Add(2.5, "pubmed", "hit")
+	counter.Inc(`a"b\c`, "line\nbreak")
+
+	var text strings.Builder
+	counter.write(&text)
+	want := `# HELP test_counter_total A counter.
+# TYPE test_counter_total counter
+test_counter_total{source="a\"b\\c",result="line\nbreak"} 1
+test_counter_total{source="pubmed",result="hit"} 3.5
+`
+	if text.String() != want {
+		t.Errorf("text =\n%s\nwant\n%s", text.String(), want)
+	}
+}
+
+func TestGaugeText(t *testing.T) {
+	unregister(t, "test_gauge")
+	gauge := NewGauge("test_gauge", "A gauge.")
+	gauge.Set(5)
+	gauge.Add(-7)
+
+	var text strings.Builder
+	gauge.write(&text)
+	want := `# HELP test_gauge A gauge.
+# TYPE test_gauge gauge
+test_gauge -2
+`
+	if text.String() != want {
+		t.Errorf("text =\n%s\nwant\n%s", text.String(), want)
+	}
+}
+
+func TestHistogramText(t *testing.T) {
+	unregister(t, "test_seconds")
+	histogram := NewHistogram("test_seconds", "A histogram.", []float64{0.1, 1, 10}, "source")
+	for _, value := range []float64{0.05, 0.1, 0.5, 20} {
+		histogram.Observe(value, "scholar")
+	}
+
+	var text strings.Builder
+	histogram.write(&text)
+	want := `# HELP test_seconds A histogram.
+# TYPE test_seconds histogram
+test_seconds_bucket{source="scholar",le="0.1"} 2
+test_seconds_bucket{source="scholar",le="1"} 3
+test_seconds_bucket{source="scholar",le="10"} 3
+test_seconds_bucket{source="scholar",le="+Inf"} 4
+test_seconds_sum{source="scholar"} 20.65
+test_seconds_count{source="scholar"} 4
+`
+	if text.String() != want {
+		t.Errorf("text =\n%s\nwant\n%s", text.String(), want)
+	}
+}
+
+func TestFormatValue(t *testing.T) {
+	tests := []struct {
+		value float64
+		want  string
+	}{
+		{0, "0"},
+		{3, "3"},
+		{0.25, "0.25"},
+		{1e21, "1e+21"},
+		{math.Inf(1), "+Inf"},
+	}
+	for _, test := range tests {
+		if got := formatValue(test.value); got != test.want {
+			t.Errorf("formatValue(%v) = %q, want %q", test.value, got, test.want)
+		}
+	}
+}
+
+func TestLabelValueCount(t *testing.T) {
+	unregister(t, "test_labels_total")
+	counter := NewCounter("test_labels_total", "Labelled.", "source")
+	defer func() {
+		if recover() == nil {
+			t.Error("Inc with missing label values didn't panic")
+		}
+	}()
+	counter.Inc()
+}
+
+func TestHandler(t *testing.T) {
+	unregister(t, "test_gauge_func")
+	NewGaugeFunc("test_gauge_func", "Read when scraped.", func() float64 { return 42 })
+
+	recorder := httptest.NewRecorder()
+	Handler().ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+	if contentType := recorder.Header().Get("Content-Type"); !strings.HasPrefix(contentType, "text/plain; version=0.0.4") {
+		t.Errorf("Content-Type = %q", contentType)
+	}
+	if body := recorder.Body.String(); !strings.Contains(body, "\ntest_gauge_func 42\n") {
+		t.Errorf("body misses test_gauge_func:\n%s", body)
+	}
+}
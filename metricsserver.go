@@ -0,0 +1,106 @@
+package main
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"scholar-bot/metrics"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+var (
+	interactionsTotal = metrics.NewCounter(
+		"scholar_bot_interactions_total",
+		"Interactions handled by type and command or component name.",
+		"type", "name",
+	)
+	gatewayConnected = metrics.NewGauge(
+		"scholar_bot_gateway_connected",
+		"Whether the Discord gateway connection is up.",
+	)
+	gatewayEvents = metrics.NewCounter(
+		"scholar_bot_gateway_events_total",
+		"Discord gateway connection events.",
+		"event",
+	)
+)
+
+// gatewayUp tracks the Discord gateway connection for /readyz.
+var gatewayUp atomic.Bool
+
+// SetupMetricsHelper registers the gateway metrics of the session, which
+// must not be open yet so its first connection is counted.
+func SetupMetricsHelper(botSession *discordgo.Session) {
+	metrics.NewGaugeFunc(
+		"scholar_bot_gateway_heartbeat_seconds",
+		"Latency of the last Discord gateway heartbeat.",
+		func() float64 { return botSession.HeartbeatLatency().Seconds() },
+	)
+
+	botSession.AddHandler(func(s *discordgo.Session, event *discordgo.Connect) {
+		setGatewayUpHelper(true, "connect")
+	})
+	botSession.AddHandler(func(s *discordgo.Session, event *discordgo.Resumed) {
+		setGatewayUpHelper(true, "resume")
+	})
+	botSession.AddHandler(func(s *discordgo.Session, event *discordgo.Disconnect) {
+		setGatewayUpHelper(false, "disconnect")
+	})
+}
+
+func setGatewayUpHelper(up bool, event string) {
+	gatewayUp.Store(up)
+	gatewayEvents.Inc(event)
+	if up {
+		gatewayConnected.Set(1)
+	} else {
+		gatewayConnected.Set(0)
+	}
+}
+
+func healthzHandler(w http.ResponseWriter, r *http.Request) {
+	w.Write([]byte("ok\n"))
+}
+
+// readyzHandler reports ready while the gateway is connected and storage
+// can be read, until the bot starts shutting down.
+func readyzHandler(w http.ResponseWriter, r *http.Request) {
+	if DrainingHelper() {
+		http.Error(w, "shutting down", http.StatusServiceUnavailable)
+		return
+	}
+	if !gatewayUp.Load() {
+		http.Error(w, "discord gateway disconnected", http.StatusServiceUnavailable)
+		return
+	}
+	if _, _, err := botStore.Get(guildSettingsBucket, ""); err != nil {
+		http.Error(w, "storage unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	w.Write([]byte("ok\n"))
+}
+
+// StartMetricsServer serves Prometheus metrics and health checks on addr
+// in the background.
+func StartMetricsServer(addr string) *http.Server {
+	mux := http.NewServeMux()
+	mux.Handle("GET /metrics", metrics.Handler())
+	mux.HandleFunc("GET /healthz", healthzHandler)
+	mux.HandleFunc("GET /readyz", readyzHandler)
+	server := &http.Server{
+		Addr:              addr,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		slog.Info("serving metrics", "addr", addr)
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			slog.Error("metrics server stopped", "err", err)
+		}
+	}()
+	return server
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+
+	"scholar-bot/apihandlers"
+	"scholar-bot/feeds"
+	"scholar-bot/storage"
+)
+
+// Buckets used by the bot's repositories.
+const (
+	guildSettingsBucket = "guild_settings"
+	savedPapersBucket   = "saved_papers"
+	readingListsBucket  = "reading_lists"
+	alertsBucket        = "alerts"
+	queryCacheBucket    = "query_cache"
+	componentRefsBucket = "component_refs"
+)
+
+// GuildSettings holds the per-guild preferences, keyed by guild ID. Zero
+// values mean the bot's default is used.
+type GuildSettings struct {
+	CitationStyle    string          `json:"citation_style,omitempty"`
+	DefaultSource    string          `json:"default_source,omitempty"`
+	MinYear          int             `json:"min_year,omitempty"`
+	ResultsPerPage   int             `json:"results_per_page,omitempty"`
+	Ephemeral        bool            `json:"ephemeral,omitempty"`
+	DisabledCommands []string        `json:"disabled_commands,omitempty"`
+	ExemptRoles      []string        `json:"exempt_roles,omitempty"`
+	Digest           *DigestSettings `json:"digest,omitempty"`
+}
+
+// DigestSettings schedules the weekly digest that replaces individual alert
+// posts. Time of day is in minutes after midnight in Timezone.
+type DigestSettings struct {
+	ChannelID string       `json:"channel_id"`
+	Weekday   time.Weekday `json:"weekday"`
+	TimeOfDay int          `json:"time_of_day"`
+	Timezone  string       `json:"timezone"`
+	LastSent  time.Time    `json:"last_sent"`
+	// RetryAt delays the next attempt after the digest failed to post
+	RetryAt time.Time `json:"retry_at"`
+}
+
+// SavedPaper is a study in a user's reading list, keyed by
+// "<user ID>/<study identifier>".
+type SavedPaper struct {
+	Identifier string                  `json:"identifier"`
+	Study      apihandlers.StudyStruct `json:"study"`
+	SavedAt    time.Time               `json:"saved_at"`
+}
+
+// savedPaperKey builds the key of a saved paper.
+func savedPaperKey(userID string, identifier string) string {
+	return userID + "/" + identifier
+}
+
+// ReadingList is a guild's named queue of papers, keyed by
+// "<guild ID>/<list name>".
+type ReadingList struct {
+	Name      string      `json:"name"`
+	CreatedBy string      `json:"created_by"`
+	CreatedAt time.Time   `json:"created_at"`
+	Papers    []ListPaper `json:"papers"`
+	Presented []ListPaper `json:"presented,omitempty"`
+}
+
+// ListPaper is a paper queued in a ReadingList with the IDs of its voters.
+type ListPaper struct {
+	Identifier string                  `json:"identifier"`
+	Study      apihandlers.StudyStruct `json:"study"`
+	AddedBy    string                  `json:"added_by"`
+	AddedAt    time.Time               `json:"added_at"`
+	Voters     []string                `json:"voters,omitempty"`
+}
+
+// readingListKey builds the key of a guild reading list.
+func readingListKey(guildID string, name string) string {
+	return guildID + "/" + name
+}
+
+// Alert is a saved search re-run on a schedule, keyed by
+// "<guild ID>/<alert ID>".
+type Alert struct {
+	ID        string        `json:"id"`
+	GuildID   string        `json:"guild_id"`
+	ChannelID string        `json:"channel_id"`
+	CreatedBy string        `json:"created_by"`
+	Query     string        `json:"query"`
+	Source    string        `json:"source"`
+	Interval  time.Duration `json:"interval"`
+	Paused    bool          `json:"paused,omitempty"`
+	// PausedReason says why the bot paused the alert by itself
+	PausedReason string    `json:"paused_reason,omitempty"`
+	LastRun      time.Time `json:"last_run"`
+	NextRun      time.Time `json:"next_run"`
+	// Seen holds the identifiers already posted, newest last
+	Seen []string `json:"seen,omitempty"`
+	// Pending holds new studies waiting for the guild's digest
+	Pending []apihandlers.StudyStruct `json:"pending,omitempty"`
+	// Recent holds the latest studies found, newest last, for the Atom feed
+	Recent []feeds.Item `json:"recent,omitempty"`
+	// FeedToken is the unguessable part of the alert's feed URL
+	FeedToken string `json:"feed_token,omitempty"`
+}
+
+// newFeedToken returns a random token for an alert's feed URL.
+func newFeedToken() string {
+	token := make([]byte, 16)
+	rand.Read(token)
+	return hex.EncodeToString(token)
+}
+
+// ComponentRef is an identifier too long for a button's custom ID, keyed
+// by componentRefKey. Refs unused for componentRefLifetime are pruned.
+type ComponentRef struct {
+	Identifier string    `json:"identifier"`
+	UsedAt     time.Time `json:"used_at"`
+}
+
+// componentRefKey returns the short key standing for an identifier too long
+// for a button's custom ID. The same identifier always gets the same key.
+func componentRefKey(identifier string) string {
+	sum := sha256.Sum256([]byte(identifier))
+	return hex.EncodeToString(sum[:8])
+}
+
+// alertKey builds the key of a guild alert.
+func alertKey(guildID string, alertID string) string {
+	return guildID + "/" + alertID
+}
+
+// storeMigrations lists every schema change in order. Append new ones at
+// the end and never edit one that has shipped.
+var storeMigrations = []storage.Migration{
+	{
+		Version: 1,
+		Name:    "initial schema",
+		Up:      func(store storage.Store) error { return nil },
+	},
+	{
+		Version: 2,
+		Name:    "alert feed tokens",
+		Up: func(store storage.Store) error {
+			alerts := storage.NewRepository[Alert](store, alertsBucket)
+			records, err := alerts.List("")
+			if err != nil {
+				return err
+			}
+			for _, record := range records {
+				err := alerts.Update(record.Key, func(alert *Alert, exists bool) (bool, error) {
+					if exists && alert.FeedToken == "" {
+						alert.FeedToken = newFeedToken()
+					}
+					return !exists, nil
+				})
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+	{
+		Version: 3,
+		Name:    "component ref timestamps",
+		Up: func(store storage.Store) error {
+			refs := storage.NewRepository[string](store, componentRefsBucket)
+			records, err := refs.List("")
+			if err != nil {
+				return err
+			}
+			updated := storage.NewRepository[ComponentRef](store, componentRefsBucket)
+			now := time.Now()
+			for _, record := range records {
+				err := updated.Put(record.Key, ComponentRef{Identifier: record.Value, UsedAt: now})
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+
+	"scholar-bot/apihandlers"
+	"scholar-bot/exporters"
+)
+
+// apiSchemas are the types described in the OpenAPI document, by name.
+var apiSchemas = map[string]reflect.Type{
+	"Study":        reflect.TypeFor[apihandlers.StudyStruct](),
+	"Author":       reflect.TypeFor[apihandlers.Author](),
+	"SearchResult": reflect.TypeFor[SearchResult](),
+}
+
+// OpenAPIHelper generates the OpenAPI document of the search API from its
+// endpoints and the Go types it returns.
+func OpenAPIHelper() map[string]any {
+	errorResponse := func(description string) map[string]any {
+		return map[string]any{
+			"description": description,
+			"content": map[string]any{
+				"application/json": map[string]any{"schema": map[string]any{"$ref": "#/components/schemas/Error"}},
+			},
+		}
+	}
+
+	paths := map[string]any{}
+	for _, endpoint := range apiEndpoints {
+		var parameters []map[string]any
+		for _, param := range endpoint.Params {
+			schema := map[string]any{"type": param.Type}
+			if param.Enum != nil {
+				schema["enum"] = param.Enum
+			}
+			parameters = append(parameters, map[string]any{
+				"name":        param.Name,
+				"in":          param.In,
+				"description": param.Description,
+				"required":    param.Required,
+				"schema":      schema,
+			})
+		}
+
+		content := map[string]any{}
+		if endpoint.Result != "" {
+			content["application/json"] = map[string]any{
+				"schema": map[string]any{"$ref": "#/components/schemas/" + endpoint.Result},
+			}
+		} else {
+			for _, name := range ExportFormatNamesHelper() {
+				content[exporters.Formats[name].ContentType] = map[string]any{
+					"schema": map[string]any{"type": "string"},
+				}
+			}
+		}
+
+		// Wildcards such as {id...} are plain parameters in OpenAPI
+		paths[strings.ReplaceAll(endpoint.Path, "...}", "}")] = map[string]any{
+			"get": map[string]any{
+				"operationId": endpoint.Name,
+				"summary":     endpoint.Summary,
+				"parameters":  parameters,
+				"responses": map[string]any{
+					"200": map[string]any{"description": "Success", "content": content},
+					"400": errorResponse("Invalid parameters"),
+					"401": errorResponse("Missing or invalid API key"),
+					"404": errorResponse("No studies found"),
+					"502": errorResponse("The source returned an error"),
+					"503": errorResponse("The source is unavailable or the bot is shutting down"),
+					"504": errorResponse("The source took too long to answer"),
+				},
+			},
+		}
+	}
+
+	schemas := map[string]any{
+		"Error": map[string]any{
+			"type":       "object",
+			"properties": map[string]any{"error": map[string]any{"type": "string"}},
+			"required":   []string{"error"},
+		},
+	}
+	for name, schemaType := range apiSchemas {
+		schemas[name] = jsonSchemaHelper(schemaType)
+	}
+
+	return map[string]any{
+		"openapi": "3.0.3",
+		"info": map[string]any{
+			"title":       "scholar-bot search API",
+			"version":     "1",
+			"description": "Searches the bot's sources and returns normalized studies.",
+		},
+		"paths": paths,
+		"components": map[string]any{
+			"schemas": schemas,
+			"securitySchemes": map[string]any{
+				"apiKey": map[string]any{"type": "apiKey", "in": "header", "name": "X-API-Key"},
+				"bearer": map[string]any{"type": "http", "scheme": "bearer"},
+			},
+		},
+		"security": []map[string]any{{"apiKey": []string{}}, {"bearer": []string{}}},
+	}
+}
+
+// jsonSchemaHelper describes a Go type as encoding/json encodes it,
+// referring to the named schemas for the types it contains.
+func jsonSchemaHelper(t reflect.Type) map[string]any {
+	switch t.Kind() {
+	case reflect.Pointer:
+		return jsonSchemaHelper(t.Elem())
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.Slice:
+		// encoding/json writes nil slices as null
+		return map[string]any{"type": "array", "items": schemaRefHelper(t.Elem()), "nullable": true}
+	case reflect.Array:
+		return map[string]any{"type": "array", "items": schemaRefHelper(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": schemaRefHelper(t.Elem())}
+	case reflect.Struct:
+		properties := map[string]any{}
+		var names []string
+		for i := range t.NumField() {
+			field := t.Field(i)
+			if !field.IsExported() || field.Tag.Get("json") == "-" {
+				continue
+			}
+			properties[field.Name] = schemaRefHelper(field.Type)
+			names = append(names, field.Name)
+		}
+		return map[string]any{"type": "object", "properties": properties, "required": names}
+	}
+	return map[string]any{}
+}
+
+// schemaRefHelper refers to the named schema of t, or describes t when it
+// has none.
+func schemaRefHelper(t reflect.Type) map[string]any {
+	for name, schemaType := range apiSchemas {
+		if schemaType == t {
+			return map[string]any{"$ref": "#/components/schemas/" + name}
+		}
+	}
+	return jsonSchemaHelper(t)
+}
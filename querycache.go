@@ -0,0 +1,63 @@
+package main
+
+import (
+	"log/slog"
+	"time"
+
+	"scholar-bot/apihandlers"
+	"scholar-bot/cache"
+	"scholar-bot/config"
+	"scholar-bot/metrics"
+	"scholar-bot/storage"
+)
+
+// queryCache holds recent search results of every source.
+var queryCache *cache.Cache[[]apihandlers.StudyStruct]
+
+// SetupCacheHelper puts a cache in front of every source, keeping the
+// results of each for its current cache_ttl. With cache.persist set, cached
+// results survive restarts.
+func SetupCacheHelper(cacheConfig config.CacheConfig) {
+	if cacheConfig.Size == 0 {
+		slog.Info("query cache disabled")
+		return
+	}
+
+	queryCache = cache.New[[]apihandlers.StudyStruct](cacheConfig.Size)
+	metrics.NewGaugeFunc(
+		"scholar_bot_cache_entries",
+		"Search results held in the in-memory cache.",
+		func() float64 { return float64(queryCache.Len()) },
+	)
+	if cacheConfig.Persist {
+		backing := storage.NewRepository[cache.Entry[[]apihandlers.StudyStruct]](botStore, queryCacheBucket)
+		if err := pruneCacheHelper(backing); err != nil {
+			slog.Warn("cannot prune query cache", "err", err)
+		}
+		queryCache.SetBacking(backing)
+	}
+
+	for name, source := range apihandlers.Sources {
+		ttl := func() time.Duration {
+			return CurrentConfigHelper().Sources.ByName()[name].CacheTTL
+		}
+		apihandlers.Sources[name] = apihandlers.WithCache(source, name, queryCache, ttl)
+	}
+}
+
+// pruneCacheHelper deletes the stored cache entries that have expired.
+func pruneCacheHelper(backing storage.Repository[cache.Entry[[]apihandlers.StudyStruct]]) error {
+	records, err := backing.List("")
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	for _, record := range records {
+		if !now.Before(record.Value.ExpiresAt) {
+			if err := backing.Delete(record.Key); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
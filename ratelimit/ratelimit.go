@@ -0,0 +1,139 @@
+// Package ratelimit implements token bucket quotas keyed by an arbitrary
+// string, such as a user or guild ID.
+package ratelimit
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Quota allows Count events per Period, in bursts of up to Count.
+type Quota struct {
+	Count  int
+	Period time.Duration
+}
+
+// ParseQuota parses quotas written as "5/1m", meaning five per minute. An
+// empty string or "0" disables the quota.
+func ParseQuota(value string) (Quota, error) {
+	if value == "" || value == "0" {
+		return Quota{}, nil
+	}
+	countText, periodText, ok := strings.Cut(value, "/")
+	if !ok {
+		return Quota{}, fmt.Errorf("quota %q should look like 5/1m", value)
+	}
+	count, err := strconv.Atoi(countText)
+	if err != nil || count < 0 {
+		return Quota{}, fmt.Errorf("invalid count in quota %q", value)
+	}
+	period, err := time.ParseDuration(periodText)
+	if err != nil || period <= 0 {
+		return Quota{}, fmt.Errorf("invalid period in quota %q", value)
+	}
+	return Quota{Count: count, Period: period}, nil
+}
+
+// Enabled reports whether the quota limits anything.
+func (q Quota) Enabled() bool {
+	return q.Count > 0 && q.Period > 0
+}
+
+func (q Quota) String() string {
+	if !q.Enabled() {
+		return "unlimited"
+	}
+	return fmt.Sprintf("%d/%s", q.Count, q.Period)
+}
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+// Limiter tracks one token bucket per key.
+type Limiter struct {
+	mu        sync.Mutex
+	quota     Quota
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+// New returns a Limiter enforcing quota.
+func New(quota Quota) *Limiter {
+	return &Limiter{quota: quota, buckets: map[string]*bucket{}}
+}
+
+// Quota returns the quota the limiter enforces.
+func (l *Limiter) Quota() Quota {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.quota
+}
+
+// SetQuota changes the quota, keeping the tokens already used.
+func (l *Limiter) SetQuota(quota Quota) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.quota = quota
+}
+
+// refill returns the bucket of key topped up to now. The caller holds mu.
+func (l *Limiter) refill(key string, now time.Time) *bucket {
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(l.quota.Count), updated: now}
+		l.buckets[key] = b
+		return b
+	}
+	rate := float64(l.quota.Count) / l.quota.Period.Seconds()
+	b.tokens = min(float64(l.quota.Count), b.tokens+now.Sub(b.updated).Seconds()*rate)
+	b.updated = now
+	return b
+}
+
+// Reserve takes a token of key if one is left, checking and taking it at
+// once so concurrent callers can't overshoot the quota. It returns the
+// function giving the token back, or how long until key may take a token
+// when none is left.
+func (l *Limiter) Reserve(key string, now time.Time) (func(), time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if !l.quota.Enabled() {
+		return func() {}, 0
+	}
+	b := l.refill(key, now)
+	if b.tokens < 1 {
+		rate := float64(l.quota.Count) / l.quota.Period.Seconds()
+		return nil, max(time.Duration((1-b.tokens)/rate*float64(time.Second)), time.Nanosecond)
+	}
+	b.tokens--
+	l.sweep(now)
+	return func() { l.cancel(key) }, 0
+}
+
+// cancel gives back a token taken by Reserve.
+func (l *Limiter) cancel(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if b, ok := l.buckets[key]; ok {
+		b.tokens = min(b.tokens+1, float64(l.quota.Count))
+	}
+}
+
+// sweep forgets buckets that have refilled, so idle keys don't pile up.
+// The caller holds mu.
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < l.quota.Period {
+		return
+	}
+	l.lastSweep = now
+	for key := range l.buckets {
+		if l.refill(key, now).tokens >= float64(l.quota.Count) {
+			delete(l.buckets, key)
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package ratelimit
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestParseQuota(t *testing.T) {
+	tests := []struct {
+		value string
+		want  Quota
+		err   bool
+	}{
+		{value: "", want: Quota{}},
+		{value: "0", want: Quota{}},
+		{value: "5/1m", want: Quota{Count: 5, Period: time.Minute}},
+		{value: "0/1s", want: Quota{Period: time.Second}},
+		{value: "5", err: true},
+		{value: "x/1m", err: true},
+		{value: "-1/1m", err: true},
+		{value: "5/x", err: true},
+		{value: "5/0s", err: true},
+	}
+	for _, test := range tests {
+		got, err := ParseQuota(test.value)
+		if (err != nil) != test.err {
+			t.Errorf("ParseQuota(%q) error = %v", test.value, err)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("ParseQuota(%q) = %+v, want %+v", test.value, got, test.want)
+		}
+	}
+}
+
+func TestLimiterReserve(t *testing.T) {
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	// reserve is a Reserve call made at start+after
+	type reserve struct {
+		after     time.Duration
+		wantDelay time.Duration
+	}
+	tests := []struct {
+		name     string
+		quota    Quota
+		reserves []reserve
+	}{
+		{
+			name:  "disabled",
+			quota: Quota{},
+			reserves: []reserve{
+				{}, {}, {},
+			},
+		},
+		{
+			name:  "burst up to the count",
+			quota: Quota{Count: 2, Period: time.Minute},
+			reserves: []reserve{
+				{},
+				{},
+				{wantDelay: 30 * time.Second},
+			},
+		},
+		{
+			name:  "refills at count per period",
+			quota: Quota{Count: 2, Period: time.Minute},
+			reserves: []reserve{
+				{},
+				{},
+				{after: 10 * time.Second, wantDelay: 20 * time.Second},
+				{after: 30 * time.Second},
+				{after: 30 * time.Second, wantDelay: 30 * time.Second},
+			},
+		},
+		{
+			name:  "refill stops at the count",
+			quota: Quota{Count: 2, Period: time.Minute},
+			reserves: []reserve{
+				{},
+				{after: time.Hour},
+				{after: time.Hour},
+				{after: time.Hour, wantDelay: 30 * time.Second},
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			limiter := New(test.quota)
+			for i, r := range test.reserves {
+				cancel, delay := limiter.Reserve("k", start.Add(r.after))
+				if delay != r.wantDelay {
+					t.Errorf("Reserve %d delay = %s, want %s", i, delay, r.wantDelay)
+				}
+				if (cancel == nil) != (r.wantDelay > 0) {
+					t.Errorf("Reserve %d returned cancel %v with delay %s", i, cancel != nil, delay)
+				}
+			}
+		})
+	}
+}
+
+func TestLimiterKeysAreSeparate(t *testing.T) {
+	now := time.Now()
+	limiter := New(Quota{Count: 1, Period: time.Minute})
+	if _, delay := limiter.Reserve("a", now); delay != 0 {
+		t.Fatalf("a delay = %s", delay)
+	}
+	if _, delay := limiter.Reserve("b", now); delay != 0 {
+		t.Errorf("b delay = %s, want 0", delay)
+	}
+}
+
+func TestLimiterCancel(t *testing.T) {
+	now := time.Now()
+	limiter := New(Quota{Count: 1, Period: time.Minute})
+	cancel, _ := limiter.Reserve("k", now)
+	cancel()
+	if _, delay := limiter.Reserve("k", now); delay != 0 {
+		t.Errorf("delay after cancelling = %s, want 0", delay)
+	}
+	cancel()
+	cancel()
+	if limiter.buckets["k"].tokens != 1 {
+		t.Errorf("tokens = %v after cancelling twice, want at most the count", limiter.buckets["k"].tokens)
+	}
+}
+
+func TestLimiterConcurrentReserve(t *testing.T) {
+	now := time.Now()
+	limiter := New(Quota{Count: 5, Period: time.Hour})
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		allowed int
+	)
+	for range 50 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, delay := limiter.Reserve("k", now); delay == 0 {
+				mu.Lock()
+				allowed++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	if allowed != 5 {
+		t.Errorf("%d concurrent reservations allowed, want 5", allowed)
+	}
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"time"
+
+	"scholar-bot/config"
+	"scholar-bot/metrics"
+	"scholar-bot/ratelimit"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// unlimitedInteractions never count against quotas: /config, /status and
+// /admin so admins can always fix the bot, and votes and the list menu as
+// they don't query any source.
+var unlimitedInteractions = []string{"config", "status", "admin", "list_vote", "list_add"}
+
+var (
+	userLimiter    = ratelimit.New(ratelimit.Quota{})
+	channelLimiter = ratelimit.New(ratelimit.Quota{})
+	guildLimiter   = ratelimit.New(ratelimit.Quota{})
+)
+
+var throttledTotal = metrics.NewCounter(
+	"scholar_bot_throttled_total",
+	"Interactions refused by a rate limit, by the scope of the limit and the command.",
+	"scope", "name",
+)
+
+// SetupRateLimitsHelper applies the interaction quotas.
+func SetupRateLimitsHelper(limits config.RateLimitsConfig) error {
+	for _, limit := range []struct {
+		value   string
+		limiter *ratelimit.Limiter
+	}{
+		{limits.User, userLimiter},
+		{limits.Channel, channelLimiter},
+		{limits.Guild, guildLimiter},
+	} {
+		quota, err := ratelimit.ParseQuota(limit.value)
+		if err != nil {
+			return err
+		}
+		limit.limiter.SetQuota(quota)
+	}
+	return nil
+}
+
+// RateLimitExemptHelper reports whether the member has one of the guild's
+// exempt roles.
+func RateLimitExemptHelper(botInteraction *discordgo.InteractionCreate, settings GuildSettings) bool {
+	if botInteraction.Member == nil {
+		return false
+	}
+	return slices.ContainsFunc(botInteraction.Member.Roles, func(role string) bool {
+		return slices.Contains(settings.ExemptRoles, role)
+	})
+}
+
+// RateLimitHelper counts the interaction against the user, channel and
+// guild quotas. When one is used up it returns how long until the
+// interaction would be allowed, and nothing is counted.
+func RateLimitHelper(botInteraction *discordgo.InteractionCreate, name string) time.Duration {
+	if slices.Contains(unlimitedInteractions, name) {
+		return 0
+	}
+	if botInteraction.GuildID != "" && RateLimitExemptHelper(botInteraction, GuildSettingsHelper(botInteraction.GuildID)) {
+		return 0
+	}
+
+	now := time.Now()
+	checks := []struct {
+		scope   string
+		key     string
+		limiter *ratelimit.Limiter
+	}{
+		{"user", InteractionUserHelper(botInteraction).ID, userLimiter},
+		{"channel", botInteraction.ChannelID, channelLimiter},
+		{"guild", botInteraction.GuildID, guildLimiter},
+	}
+	var reserved []func()
+	for _, check := range checks {
+		if check.key == "" {
+			continue
+		}
+		cancel, delay := check.limiter.Reserve(check.key, now)
+		if delay > 0 {
+			for _, cancel := range reserved {
+				cancel()
+			}
+			throttledTotal.Inc(check.scope, name)
+			return delay
+		}
+		reserved = append(reserved, cancel)
+	}
+	return 0
+}
+
+func RateLimitMessageHelper(delay time.Duration) string {
+	seconds := int(math.Ceil(delay.Seconds()))
+	if seconds == 1 {
+		return "You're going too fast, please try again in 1 second"
+	}
+	return fmt.Sprintf("You're going too fast, please try again in %d seconds", seconds)
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"sync/atomic"
+
+	"scholar-bot/apihandlers"
+	"scholar-bot/config"
+	"scholar-bot/storage"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// botArgs are the command-line arguments, read again on reload.
+var botArgs []string
+
+// botConfig is the configuration in effect.
+var botConfig atomic.Pointer[config.Config]
+
+// reloadMu keeps reloads from overlapping.
+var reloadMu sync.Mutex
+
+func CurrentConfigHelper() *config.Config {
+	return botConfig.Load()
+}
+
+// LoadConfigHelper loads and validates the configuration from the file,
+// environment and command line.
+func LoadConfigHelper(args []string) (*config.Config, config.Options, error) {
+	cfg, options, err := config.Load(args)
+	if err != nil {
+		return nil, options, err
+	}
+	err = cfg.Validate()
+	if cfg.Discord.Token == "" && !options.PrintConfig {
+		err = errors.Join(err, errors.New("discord.token: required, set it in the configuration file or in scholar_bot"))
+	}
+	return cfg, options, err
+}
+
+// SetupHelper sets up logging, storage, the cache and the sources.
+func SetupHelper(cfg *config.Config) error {
+	logger, err := LoggerHelper(os.Stderr, cfg.Log)
+	if err != nil {
+		return err
+	}
+	slog.SetDefault(logger)
+
+	botSession.Token = "Bot " + cfg.Discord.Token
+	botSession.Identify.Token = botSession.Token
+
+	botStore, err = storage.Open(cfg.Storage.Path, storeMigrations)
+	if err != nil {
+		return fmt.Errorf("cannot open storage %q: %w", cfg.Storage.Path, err)
+	}
+	guildSettings = storage.NewRepository[GuildSettings](botStore, guildSettingsBucket)
+	savedPapers = storage.NewRepository[SavedPaper](botStore, savedPapersBucket)
+	readingLists = storage.NewRepository[ReadingList](botStore, readingListsBucket)
+	alerts = storage.NewRepository[Alert](botStore, alertsBucket)
+	componentRefs = storage.NewRepository[ComponentRef](botStore, componentRefsBucket)
+	if err := pruneComponentRefsHelper(); err != nil {
+		slog.Warn("cannot prune component refs", "err", err)
+	}
+
+	SetupCacheHelper(cfg.Cache)
+	return ApplyConfigHelper(cfg)
+}
+
+// ApplyConfigHelper applies the settings that can change while the bot
+// runs: the log level, the sources and the rate limits.
+func ApplyConfigHelper(cfg *config.Config) error {
+	if err := SetupRateLimitsHelper(cfg.RateLimits); err != nil {
+		return err
+	}
+	if err := logLevel.UnmarshalText([]byte(cfg.Log.Level)); err != nil {
+		return err
+	}
+	SetupSchedulersHelper(cfg.Sources)
+	SetupBreakersHelper(cfg.Sources)
+	apihandlers.SetNCBICredentials(apihandlers.NCBICredentials{
+		APIKey: cfg.NCBI.APIKey,
+		Email:  cfg.NCBI.Email,
+		Tool:   cfg.NCBI.Tool,
+	})
+
+	source := apihandlers.Sources["scholar"]
+	if cfg.Sources.Fallback != "" {
+		source = apihandlers.WithFallback(source, apihandlers.Sources[cfg.Sources.Fallback])
+	}
+	scholarSource.Store(&source)
+	botConfig.Store(cfg)
+	return nil
+}
+
+// ReloadResult describes what a reload changed.
+type ReloadResult struct {
+	// Changed lists the settings now in effect.
+	Changed []string
+	// Restart lists the changed settings that only apply after a restart.
+	Restart []string
+	// CommandsSynced is set when the command definitions changed and were
+	// synced to Discord.
+	CommandsSynced bool
+}
+
+// ReloadConfigHelper reads the configuration again and applies it. An
+// invalid configuration leaves the current one in effect.
+func ReloadConfigHelper(botSession *discordgo.Session) (ReloadResult, error) {
+	reloadMu.Lock()
+	defer reloadMu.Unlock()
+
+	var result ReloadResult
+	cfg, _, err := LoadConfigHelper(botArgs)
+	if err != nil {
+		return result, err
+	}
+	result.Changed, result.Restart = CurrentConfigHelper().Reload(cfg)
+	if err := ApplyConfigHelper(cfg); err != nil {
+		return result, err
+	}
+	slog.Info("configuration reloaded", "changed", result.Changed, "restart_required", result.Restart)
+
+	if commands := CommandsHelper(); CommandsChangedHelper(commands) {
+		if err := SyncCommandsHelper(botSession, cfg.Discord.Guild, commands); err != nil {
+			return result, fmt.Errorf("configuration applied but %w", err)
+		}
+		result.CommandsSynced = true
+	}
+	return result, nil
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// Exit codes of the bot.
+const (
+	// exitFatal: the bot couldn't start or hit an unrecoverable error.
+	exitFatal = 1
+	// exitConfig: the configuration or the arguments are invalid.
+	exitConfig = 2
+	// exitIncomplete: shutdown had to cancel work or couldn't save state.
+	exitIncomplete = 3
+	// exitForced: a second signal cut shutdown short.
+	exitForced = 4
+)
+
+// cancelGrace is how long cancelled work gets to return on shutdown.
+const cancelGrace = 5 * time.Second
+
+var (
+	workMu   sync.Mutex
+	draining bool
+	work     sync.WaitGroup
+	// stopping is closed when the bot starts shutting down.
+	stopping = make(chan struct{})
+)
+
+// workCtx is the parent of the contexts of interactions and background
+// jobs. It is cancelled when they don't finish in time on shutdown.
+var workCtx, cancelWork = context.WithCancel(context.Background())
+
+// StartWorkHelper counts a unit of work for shutdown to wait for, calling
+// done once it finishes. It returns false once the bot is shutting down.
+func StartWorkHelper() (done func(), ok bool) {
+	workMu.Lock()
+	defer workMu.Unlock()
+	if draining {
+		return nil, false
+	}
+	work.Add(1)
+	return work.Done, true
+}
+
+// DrainingHelper reports whether the bot stopped accepting work.
+func DrainingHelper() bool {
+	workMu.Lock()
+	defer workMu.Unlock()
+	return draining
+}
+
+// GoHelper runs fn in the background as work shutdown waits for. Its
+// context keeps the values of ctx but outlives it, until shutdown cancels
+// the remaining work. It returns false if the bot is shutting down.
+func GoHelper(ctx context.Context, fn func(ctx context.Context)) bool {
+	done, ok := StartWorkHelper()
+	if !ok {
+		return false
+	}
+	ctx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	stop := context.AfterFunc(workCtx, cancel)
+	go func() {
+		defer done()
+		defer stop()
+		defer cancel()
+		fn(ctx)
+	}()
+	return true
+}
+
+// DrainHelper stops accepting work and waits up to timeout for the work in
+// flight, then cancels what is left. It reports whether everything
+// finished in time.
+func DrainHelper(timeout time.Duration) bool {
+	workMu.Lock()
+	draining = true
+	close(stopping)
+	workMu.Unlock()
+
+	finished := make(chan struct{})
+	go func() {
+		work.Wait()
+		close(finished)
+	}()
+
+	select {
+	case <-finished:
+		cancelWork()
+		return true
+	case <-time.After(timeout):
+	}
+	slog.Warn("work still running after the shutdown timeout, cancelling it", "timeout", timeout)
+	cancelWork()
+	select {
+	case <-finished:
+	case <-time.After(cancelGrace):
+		slog.Error("cancelled work did not return")
+	}
+	return false
+}
+
+// ShutdownHelper waits for a signal, then drains the work in flight, stops
+// the HTTP servers, disconnects and closes storage. It returns the exit
+// code. A second signal exits at once.
+func ShutdownHelper(signals <-chan os.Signal, servers ...*http.Server) int {
+	received := <-signals
+	slog.Info("shutting down", "signal", received.String())
+	go func() {
+		received := <-signals
+		slog.Error("forced to exit", "signal", received.String())
+		os.Exit(exitForced)
+	}()
+
+	code := 0
+	if !DrainHelper(CurrentConfigHelper().Discord.ShutdownTimeout) {
+		code = exitIncomplete
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), cancelGrace)
+	defer cancel()
+	for _, server := range servers {
+		if server == nil {
+			continue
+		}
+		if err := server.Shutdown(ctx); err != nil {
+			slog.Warn("cannot stop HTTP server", "addr", server.Addr, "err", err)
+		}
+	}
+
+	if CurrentConfigHelper().Discord.RemoveCommands {
+		slog.Info("removing commands")
+		if err := RemoveCommandsHelper(botSession, CurrentConfigHelper().Discord.Guild); err != nil {
+			slog.Error("cannot remove commands", "err", err)
+		}
+	}
+	if err := botSession.Close(); err != nil {
+		slog.Warn("cannot close the session", "err", err)
+	}
+	if err := botStore.Close(); err != nil {
+		slog.Error("cannot close storage", "err", err)
+		code = exitIncomplete
+	}
+
+	slog.Info("shut down", "exit_code", code)
+	return code
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"scholar-bot/apihandlers"
+	"scholar-bot/config"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// startedAt is when the bot started, for the uptime in /status.
+var startedAt = time.Now()
+
+// SetupBreakersHelper applies the request timeout, the circuit breaker
+// policy and which sources are enabled.
+func SetupBreakersHelper(sources config.SourcesConfig) {
+	apihandlers.SetRequestTimeout(sources.Timeout)
+	for _, breaker := range apihandlers.Breakers {
+		breaker.SetPolicy(sources.BreakerFailures, sources.BreakerOpenFor)
+	}
+
+	var enabled []string
+	for name, source := range sources.ByName() {
+		if source.Enabled {
+			enabled = append(enabled, name)
+		}
+	}
+	apihandlers.SetEnabledSources(enabled)
+}
+
+// SourceStatusHelper describes the health of one source.
+func SourceStatusHelper(name string) string {
+	status := apihandlers.Breakers[name].Status()
+	scheduler := apihandlers.Schedulers[name]
+
+	if !apihandlers.SourceEnabled(name) {
+		return "⚫ Disabled"
+	}
+
+	var lines []string
+	switch status.State {
+	case apihandlers.BreakerOpen:
+		lines = append(lines, fmt.Sprintf(
+			"🔴 Not responding, retrying in %s", status.RetryIn.Round(time.Second),
+		))
+	case apihandlers.BreakerHalfOpen:
+		lines = append(lines, "🟡 Recovering, trying a request")
+	default:
+		lines = append(lines, "🟢 Up")
+	}
+	if status.Failures != 0 {
+		lines = append(lines, fmt.Sprintf("Consecutive failures: %d", status.Failures))
+	}
+	if status.LastError != nil && status.State != apihandlers.BreakerClosed {
+		lines = append(lines, fmt.Sprintf("Last error: `%v`", status.LastError))
+	}
+	if name == "scholar" {
+		if remaining := apihandlers.ScholarCoolDownRemaining(); remaining > 0 {
+			lines = append(lines, fmt.Sprintf("Blocked by CAPTCHA, cooling down for %s", remaining.Round(time.Second)))
+		}
+	}
+	lines = append(lines, fmt.Sprintf("Requests running: %d, queued: %d", scheduler.Running(), scheduler.Waiting()))
+	return strings.Join(lines, "\n")
+}
+
+func StatusCommandHandler(ctx context.Context, botSession *discordgo.Session, botInteraction *discordgo.InteractionCreate) {
+	var fields []*discordgo.MessageEmbedField
+	names := make([]string, 0, len(apihandlers.Sources))
+	for name := range apihandlers.Sources {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		fields = append(fields, &discordgo.MessageEmbedField{
+			Name:  apihandlers.Sources[name].Name(),
+			Value: SourceStatusHelper(name),
+		})
+	}
+
+	cacheEntries := "disabled"
+	if queryCache != nil {
+		cacheEntries = fmt.Sprint(queryCache.Len())
+	}
+	fields = append(fields, &discordgo.MessageEmbedField{
+		Name: "Bot",
+		Value: fmt.Sprintf(
+			"Uptime: %s\nGateway latency: %s\nCached searches: %s",
+			time.Since(startedAt).Round(time.Second),
+			botSession.HeartbeatLatency().Round(time.Millisecond),
+			cacheEntries,
+		),
+	})
+
+	RespondHelper(ctx, botSession, botInteraction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Embeds: []*discordgo.MessageEmbed{{
+				Title:  "Bot status",
+				Fields: fields,
+			}},
+			Flags: discordgo.MessageFlagsEphemeral,
+		},
+	})
+}
@@ -0,0 +1,207 @@
+package storage
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"sync"
+)
+
+// compactThreshold is how many superseded log entries are tolerated before
+// the log is rewritten from the live data.
+const compactThreshold = 1000
+
+// logEntry is one line of the append-only log. A nil Value, written as
+// null or left out, is a delete; an empty one is kept as "".
+type logEntry struct {
+	Bucket string `json:"b"`
+	Key    string `json:"k"`
+	Value  []byte `json:"v"`
+}
+
+// FileStore is a MemoryStore persisted to an append-only JSON lines log,
+// which is replayed on open and compacted once it holds enough stale entries.
+type FileStore struct {
+	*MemoryStore
+
+	path      string
+	fileMutex sync.Mutex
+	file      *os.File
+	stale     int
+}
+
+// OpenFileStore opens or creates the log at path and loads it into memory.
+func OpenFileStore(path string) (*FileStore, error) {
+	store := &FileStore{MemoryStore: NewMemoryStore(), path: path}
+
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("error opening storage file: %w", err)
+	}
+
+	// Replay the log, dropping a torn last line left by a crash
+	reader := bufio.NewReader(file)
+	var offset int64
+	for {
+		line, err := reader.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			if len(line) != 0 {
+				if err := file.Truncate(offset); err != nil {
+					file.Close()
+					return nil, fmt.Errorf("error truncating storage file: %w", err)
+				}
+			}
+			break
+		}
+		if err != nil {
+			file.Close()
+			return nil, fmt.Errorf("error reading storage file: %w", err)
+		}
+		var entry logEntry
+		if err := json.Unmarshal(line, &entry); err != nil {
+			file.Close()
+			return nil, fmt.Errorf("error decoding storage file: %w", err)
+		}
+		if _, exists := store.buckets[entry.Bucket][entry.Key]; exists || entry.Value == nil {
+			store.stale++
+		}
+		store.set(entry.Bucket, entry.Key, entry.Value)
+		offset += int64(len(line))
+	}
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("error seeking storage file: %w", err)
+	}
+	store.file = file
+
+	if err := store.compact(); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return store, nil
+}
+
+func (f *FileStore) Put(bucket string, key string, value []byte) error {
+	return f.Update(bucket, key, func([]byte, bool) ([]byte, error) {
+		return value, nil
+	})
+}
+
+func (f *FileStore) Delete(bucket string, key string) error {
+	return f.Update(bucket, key, func([]byte, bool) ([]byte, error) {
+		return nil, nil
+	})
+}
+
+func (f *FileStore) Update(bucket string, key string, fn func(value []byte, exists bool) ([]byte, error)) error {
+	// The memory lock is held while appending so the log keeps the same
+	// order as the writes to memory
+	err := f.MemoryStore.Update(bucket, key, func(value []byte, exists bool) ([]byte, error) {
+		newValue, err := fn(value, exists)
+		if err != nil {
+			return nil, err
+		}
+		if !exists && newValue == nil {
+			return nil, nil
+		}
+		if err := f.append(logEntry{Bucket: bucket, Key: key, Value: newValue}); err != nil {
+			return nil, err
+		}
+		if exists {
+			f.stale++
+		}
+		return newValue, nil
+	})
+	if err != nil {
+		return err
+	}
+	// The write is already on disk, so a failed compaction is only logged
+	// and retried on the next write
+	if err := f.Compact(); err != nil && !errors.Is(err, ErrClosed) {
+		slog.Error("cannot compact storage", "path", f.path, "err", err)
+	}
+	return nil
+}
+
+func (f *FileStore) append(entry logEntry) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("error encoding storage entry: %w", err)
+	}
+	f.fileMutex.Lock()
+	defer f.fileMutex.Unlock()
+	if _, err := f.file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("error writing storage file: %w", err)
+	}
+	return f.file.Sync()
+}
+
+// Compact rewrites the log from the live data if it has grown stale.
+func (f *FileStore) Compact() error {
+	f.MemoryStore.mutex.Lock()
+	defer f.MemoryStore.mutex.Unlock()
+	if f.closed {
+		return ErrClosed
+	}
+	return f.compact()
+}
+
+// compact is called with the memory lock held, or before the store is shared.
+func (f *FileStore) compact() error {
+	if f.stale < compactThreshold {
+		return nil
+	}
+
+	tmpPath := f.path + ".tmp"
+	tmpFile, err := os.OpenFile(tmpPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return fmt.Errorf("error creating compacted storage file: %w", err)
+	}
+	writer := bufio.NewWriter(tmpFile)
+	for bucket, values := range f.buckets {
+		for key, value := range values {
+			line, err := json.Marshal(logEntry{Bucket: bucket, Key: key, Value: value})
+			if err != nil {
+				tmpFile.Close()
+				return fmt.Errorf("error encoding storage entry: %w", err)
+			}
+			writer.Write(append(line, '\n'))
+		}
+	}
+	if err := writer.Flush(); err != nil {
+		tmpFile.Close()
+		return fmt.Errorf("error writing compacted storage file: %w", err)
+	}
+	if err := tmpFile.Sync(); err != nil {
+		tmpFile.Close()
+		return fmt.Errorf("error writing compacted storage file: %w", err)
+	}
+	tmpFile.Close()
+
+	f.fileMutex.Lock()
+	defer f.fileMutex.Unlock()
+	if err := os.Rename(tmpPath, f.path); err != nil {
+		return fmt.Errorf("error replacing storage file: %w", err)
+	}
+	file, err := os.OpenFile(f.path, os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return fmt.Errorf("error reopening storage file: %w", err)
+	}
+	f.file.Close()
+	f.file = file
+	f.stale = 0
+	return nil
+}
+
+func (f *FileStore) Close() error {
+	if err := f.MemoryStore.Close(); err != nil {
+		return err
+	}
+	f.fileMutex.Lock()
+	defer f.fileMutex.Unlock()
+	return f.file.Close()
+}
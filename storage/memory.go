@@ -0,0 +1,116 @@
+package storage
+
+import (
+	"sort"
+	"strings"
+	"sync"
+)
+
+// MemoryStore keeps everything in maps. It is used for tests and when no
+// storage path is configured.
+type MemoryStore struct {
+	mutex   sync.RWMutex
+	buckets map[string]map[string][]byte
+	closed  bool
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{buckets: make(map[string]map[string][]byte)}
+}
+
+func (m *MemoryStore) Get(bucket string, key string) ([]byte, bool, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	if m.closed {
+		return nil, false, ErrClosed
+	}
+	value, ok := m.buckets[bucket][key]
+	return cloneBytes(value), ok, nil
+}
+
+func (m *MemoryStore) Put(bucket string, key string, value []byte) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if m.closed {
+		return ErrClosed
+	}
+	m.set(bucket, key, value)
+	return nil
+}
+
+func (m *MemoryStore) Delete(bucket string, key string) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if m.closed {
+		return ErrClosed
+	}
+	m.set(bucket, key, nil)
+	return nil
+}
+
+func (m *MemoryStore) Update(bucket string, key string, fn func(value []byte, exists bool) ([]byte, error)) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if m.closed {
+		return ErrClosed
+	}
+	value, ok := m.buckets[bucket][key]
+	newValue, err := fn(cloneBytes(value), ok)
+	if err != nil {
+		return err
+	}
+	m.set(bucket, key, newValue)
+	return nil
+}
+
+func (m *MemoryStore) Scan(bucket string, prefix string, fn func(key string, value []byte) error) error {
+	m.mutex.RLock()
+	if m.closed {
+		m.mutex.RUnlock()
+		return ErrClosed
+	}
+	// Copy the matching entries so fn can write to the store
+	var keys []string
+	values := make(map[string][]byte)
+	for key, value := range m.buckets[bucket] {
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+			values[key] = cloneBytes(value)
+		}
+	}
+	m.mutex.RUnlock()
+
+	sort.Strings(keys)
+	for _, key := range keys {
+		if err := fn(key, values[key]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *MemoryStore) Close() error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.closed = true
+	return nil
+}
+
+// set writes or, for a nil value, deletes a key. The caller holds the lock.
+func (m *MemoryStore) set(bucket string, key string, value []byte) {
+	if value == nil {
+		delete(m.buckets[bucket], key)
+		return
+	}
+	if m.buckets[bucket] == nil {
+		m.buckets[bucket] = make(map[string][]byte)
+	}
+	m.buckets[bucket][key] = cloneBytes(value)
+}
+
+func cloneBytes(value []byte) []byte {
+	if value == nil {
+		return nil
+	}
+	return append([]byte{}, value...)
+}
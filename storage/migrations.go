@@ -0,0 +1,54 @@
+package storage
+
+import (
+	"fmt"
+	"log/slog"
+	"strconv"
+)
+
+// metaBucket holds bookkeeping such as the schema version.
+const metaBucket = "meta"
+
+// Migration upgrades the stored data from Version-1 to Version.
+type Migration struct {
+	Version int
+	Name    string
+	Up      func(store Store) error
+}
+
+// SchemaVersion returns the version the store was last migrated to.
+func SchemaVersion(store Store) (int, error) {
+	value, ok, err := store.Get(metaBucket, "schema_version")
+	if err != nil || !ok {
+		return 0, err
+	}
+	version, err := strconv.Atoi(string(value))
+	if err != nil {
+		return 0, fmt.Errorf("invalid schema version %q: %w", value, err)
+	}
+	return version, nil
+}
+
+// Migrate runs the migrations the store hasn't seen yet, recording the
+// version after each one so a failure can be resumed.
+func Migrate(store Store, migrations []Migration) error {
+	current, err := SchemaVersion(store)
+	if err != nil {
+		return err
+	}
+	for _, migration := range migrations {
+		if migration.Version <= current {
+			continue
+		}
+		slog.Info("migrating storage", "version", migration.Version, "migration", migration.Name)
+		if err := migration.Up(store); err != nil {
+			return fmt.Errorf("migration %d (%s) failed: %w", migration.Version, migration.Name, err)
+		}
+		err := store.Put(metaBucket, "schema_version", []byte(strconv.Itoa(migration.Version)))
+		if err != nil {
+			return err
+		}
+		current = migration.Version
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Record is a value stored under a key.
+type Record[T any] struct {
+	Key   string
+	Value T
+}
+
+// Repository gives typed access to the values of one bucket.
+type Repository[T any] interface {
+	Get(key string) (T, bool, error)
+	Put(key string, value T) error
+	// Update atomically loads, modifies and saves the value of key. When
+	// fn sets remove, the key is deleted instead.
+	Update(key string, fn func(value *T, exists bool) (remove bool, err error)) error
+	Delete(key string) error
+	// List returns every record whose key starts with prefix, in key order.
+	List(prefix string) ([]Record[T], error)
+}
+
+type jsonRepository[T any] struct {
+	store  Store
+	bucket string
+}
+
+// NewRepository returns a Repository storing values of T as JSON in bucket.
+func NewRepository[T any](store Store, bucket string) Repository[T] {
+	return &jsonRepository[T]{store: store, bucket: bucket}
+}
+
+func (r *jsonRepository[T]) Get(key string) (T, bool, error) {
+	var value T
+	data, ok, err := r.store.Get(r.bucket, key)
+	if err != nil || !ok {
+		return value, ok, err
+	}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return value, false, fmt.Errorf("error decoding %s/%s: %w", r.bucket, key, err)
+	}
+	return value, true, nil
+}
+
+func (r *jsonRepository[T]) Put(key string, value T) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("error encoding %s/%s: %w", r.bucket, key, err)
+	}
+	return r.store.Put(r.bucket, key, data)
+}
+
+func (r *jsonRepository[T]) Update(key string, fn func(value *T, exists bool) (bool, error)) error {
+	return r.store.Update(r.bucket, key, func(data []byte, exists bool) ([]byte, error) {
+		var value T
+		if exists {
+			if err := json.Unmarshal(data, &value); err != nil {
+				return nil, fmt.Errorf("error decoding %s/%s: %w", r.bucket, key, err)
+			}
+		}
+		remove, err := fn(&value, exists)
+		if err != nil {
+			return data, err
+		}
+		if remove {
+			return nil, nil
+		}
+		newData, err := json.Marshal(value)
+		if err != nil {
+			return nil, fmt.Errorf("error encoding %s/%s: %w", r.bucket, key, err)
+		}
+		return newData, nil
+	})
+}
+
+func (r *jsonRepository[T]) Delete(key string) error {
+	return r.store.Delete(r.bucket, key)
+}
+
+func (r *jsonRepository[T]) List(prefix string) ([]Record[T], error) {
+	var records []Record[T]
+	err := r.store.Scan(r.bucket, prefix, func(key string, data []byte) error {
+		var value T
+		if err := json.Unmarshal(data, &value); err != nil {
+			return fmt.Errorf("error decoding %s/%s: %w", r.bucket, key, err)
+		}
+		records = append(records, Record[T]{Key: key, Value: value})
+		return nil
+	})
+	return records, err
+}
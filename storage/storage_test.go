@@ -0,0 +1,407 @@
+package storage
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+// openStores returns a fresh memory store and a fresh file store, so the
+// shared behaviour is tested on both.
+func openStores(t *testing.T) map[string]Store {
+	t.Helper()
+	fileStore, err := OpenFileStore(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { fileStore.Close() })
+	return map[string]Store{
+		"memory": NewMemoryStore(),
+		"file":   fileStore,
+	}
+}
+
+func TestStore(t *testing.T) {
+	tests := []struct {
+		name  string
+		run   func(store Store) error
+		key   string
+		want  string
+		found bool
+	}{
+		{
+			name: "put",
+			run:  func(store Store) error { return store.Put("b", "k", []byte("v")) },
+			key:  "k", want: "v", found: true,
+		},
+		{
+			name: "overwrite",
+			run: func(store Store) error {
+				store.Put("b", "k", []byte("v1"))
+				return store.Put("b", "k", []byte("v2"))
+			},
+			key: "k", want: "v2", found: true,
+		},
+		{
+			name: "delete",
+			run: func(store Store) error {
+				store.Put("b", "k", []byte("v"))
+				return store.Delete("b", "k")
+			},
+			key: "k",
+		},
+		{
+			name: "delete missing",
+			run:  func(store Store) error { return store.Delete("b", "k") },
+			key:  "k",
+		},
+		{
+			name: "update existing",
+			run: func(store Store) error {
+				store.Put("b", "k", []byte("v"))
+				return store.Update("b", "k", func(value []byte, exists bool) ([]byte, error) {
+					if !exists {
+						return nil, errors.New("expected the key to exist")
+					}
+					return append(value, '2'), nil
+				})
+			},
+			key: "k", want: "v2", found: true,
+		},
+		{
+			name: "update to nil deletes",
+			run: func(store Store) error {
+				store.Put("b", "k", []byte("v"))
+				return store.Update("b", "k", func([]byte, bool) ([]byte, error) { return nil, nil })
+			},
+			key: "k",
+		},
+		{
+			name: "failed update keeps value",
+			run: func(store Store) error {
+				store.Put("b", "k", []byte("v"))
+				store.Update("b", "k", func([]byte, bool) ([]byte, error) {
+					return []byte("lost"), errors.New("failed")
+				})
+				return nil
+			},
+			key: "k", want: "v", found: true,
+		},
+		{
+			name: "buckets are separate",
+			run:  func(store Store) error { return store.Put("other", "k", []byte("v")) },
+			key:  "k",
+		},
+	}
+	for _, test := range tests {
+		for kind, store := range openStores(t) {
+			t.Run(test.name+"/"+kind, func(t *testing.T) {
+				if err := test.run(store); err != nil {
+					t.Fatal(err)
+				}
+				value, found, err := store.Get("b", test.key)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if found != test.found || string(value) != test.want {
+					t.Errorf("Get = %q, %v, want %q, %v", value, found, test.want, test.found)
+				}
+			})
+		}
+	}
+}
+
+func TestStoreScan(t *testing.T) {
+	for kind, store := range openStores(t) {
+		t.Run(kind, func(t *testing.T) {
+			for _, key := range []string{"g2/b", "g1/b", "g1/a", "g10/a"} {
+				store.Put("b", key, []byte(key))
+			}
+			store.Put("other", "g1/c", []byte("other"))
+
+			var keys []string
+			err := store.Scan("b", "g1/", func(key string, value []byte) error {
+				if string(value) != key {
+					t.Errorf("value of %q = %q", key, value)
+				}
+				keys = append(keys, key)
+				// Writing from fn must not deadlock
+				return store.Put("b", key, value)
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := []string{"g1/a", "g1/b"}; !reflect.DeepEqual(keys, want) {
+				t.Errorf("Scan keys = %q, want %q", keys, want)
+			}
+		})
+	}
+}
+
+func TestStoreClosed(t *testing.T) {
+	for kind, store := range openStores(t) {
+		t.Run(kind, func(t *testing.T) {
+			if err := store.Close(); err != nil {
+				t.Fatal(err)
+			}
+			if _, _, err := store.Get("b", "k"); !errors.Is(err, ErrClosed) {
+				t.Errorf("Get after Close = %v, want ErrClosed", err)
+			}
+			if err := store.Put("b", "k", []byte("v")); !errors.Is(err, ErrClosed) {
+				t.Errorf("Put after Close = %v, want ErrClosed", err)
+			}
+		})
+	}
+}
+
+func TestMemoryStoreCopiesValues(t *testing.T) {
+	store := NewMemoryStore()
+	value := []byte("v")
+	store.Put("b", "k", value)
+	value[0] = 'x'
+	got, _, _ := store.Get("b", "k")
+	got[0] = 'y'
+	if got, _, _ := store.Get("b", "k"); string(got) != "v" {
+		t.Errorf("stored value changed to %q", got)
+	}
+}
+
+func TestFileStoreReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+	store, err := OpenFileStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.Put("b", "kept", []byte("v1"))
+	store.Put("b", "kept", []byte("v2"))
+	store.Put("b", "deleted", []byte("v"))
+	store.Delete("b", "deleted")
+	store.Put("b", "empty", []byte{})
+	if err := store.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		suffix string
+		err    bool
+	}{
+		{name: "clean log"},
+		// Older logs left the value out of deletes
+		{name: "delete without a value", suffix: `{"b":"b","k":"old","v":"dg=="}` + "\n" + `{"b":"b","k":"old"}` + "\n"},
+		{name: "torn last line", suffix: `{"b":"b","k":"torn","v":"d`},
+		{name: "corrupt line", suffix: "not json\n", err: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			log, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			testPath := filepath.Join(t.TempDir(), "test.db")
+			if err := os.WriteFile(testPath, append(log, test.suffix...), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			store, err := OpenFileStore(testPath)
+			if test.err {
+				if err == nil {
+					store.Close()
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer store.Close()
+			if value, _, _ := store.Get("b", "kept"); string(value) != "v2" {
+				t.Errorf("kept = %q, want v2", value)
+			}
+			if value, found, _ := store.Get("b", "empty"); !found || len(value) != 0 {
+				t.Errorf("empty = %q, %v, want an empty value", value, found)
+			}
+			for _, key := range []string{"deleted", "old", "torn"} {
+				if _, found, _ := store.Get("b", key); found {
+					t.Errorf("%s was replayed", key)
+				}
+			}
+
+			// A torn line is dropped, so the next write starts a clean line
+			if err := store.Put("b", "after", []byte("v")); err != nil {
+				t.Fatal(err)
+			}
+			store.Close()
+			reopened, err := OpenFileStore(testPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer reopened.Close()
+			if value, _, _ := reopened.Get("b", "after"); string(value) != "v" {
+				t.Errorf("after = %q, want v", value)
+			}
+		})
+	}
+}
+
+func TestFileStoreCompaction(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+	store, err := OpenFileStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.Put("b", "other", []byte("v"))
+	for i := 0; i <= compactThreshold; i++ {
+		if err := store.Put("b", "k", []byte(strconv.Itoa(i))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if store.stale != 0 {
+		t.Errorf("stale = %d after compaction, want 0", store.stale)
+	}
+	store.Put("b", "k", []byte("last"))
+	store.Close()
+
+	log, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The two live keys and the write after compaction
+	if lines := bytes.Count(log, []byte("\n")); lines != 3 {
+		t.Errorf("compacted log has %d lines, want 3", lines)
+	}
+
+	reopened, err := OpenFileStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reopened.Close()
+	for key, want := range map[string]string{"k": "last", "other": "v"} {
+		if value, _, _ := reopened.Get("b", key); string(value) != want {
+			t.Errorf("%s = %q, want %q", key, value, want)
+		}
+	}
+}
+
+func TestFileStoreCompactionFailureKeepsWrite(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "test.db")
+	store, err := OpenFileStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	store.stale = compactThreshold
+	// The temporary file of the compaction can't be created over a directory
+	if err := os.Mkdir(path+".tmp", 0o700); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Put("b", "k", []byte("v")); err != nil {
+		t.Errorf("Put = %v, want the write to succeed", err)
+	}
+	if value, _, _ := store.Get("b", "k"); string(value) != "v" {
+		t.Errorf("k = %q, want v", value)
+	}
+}
+
+func TestMigrate(t *testing.T) {
+	var ran []int
+	migration := func(version int) Migration {
+		return Migration{
+			Version: version,
+			Name:    "test",
+			Up: func(store Store) error {
+				ran = append(ran, version)
+				return store.Put("b", "version", []byte(strconv.Itoa(version)))
+			},
+		}
+	}
+	failing := Migration{Version: 3, Name: "failing", Up: func(Store) error {
+		return errors.New("failed")
+	}}
+
+	tests := []struct {
+		name        string
+		migrations  []Migration
+		wantRan     []int
+		wantVersion int
+		err         bool
+	}{
+		{name: "first run", migrations: []Migration{migration(1), migration(2)}, wantRan: []int{1, 2}, wantVersion: 2},
+		{name: "up to date", migrations: []Migration{migration(1), migration(2)}, wantVersion: 2},
+		{name: "new migration", migrations: []Migration{migration(1), migration(2), migration(3)}, wantRan: []int{3}, wantVersion: 3},
+	}
+	store := NewMemoryStore()
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ran = nil
+			err := Migrate(store, test.migrations)
+			if (err != nil) != test.err {
+				t.Fatalf("Migrate = %v", err)
+			}
+			if !reflect.DeepEqual(ran, test.wantRan) {
+				t.Errorf("ran %v, want %v", ran, test.wantRan)
+			}
+			if version, _ := SchemaVersion(store); version != test.wantVersion {
+				t.Errorf("SchemaVersion = %d, want %d", version, test.wantVersion)
+			}
+		})
+	}
+
+	t.Run("failure is resumed", func(t *testing.T) {
+		store := NewMemoryStore()
+		ran = nil
+		if err := Migrate(store, []Migration{migration(1), migration(2), failing}); err == nil {
+			t.Fatal("expected an error")
+		}
+		if version, _ := SchemaVersion(store); version != 2 {
+			t.Errorf("SchemaVersion = %d after a failure, want 2", version)
+		}
+		ran = nil
+		if err := Migrate(store, []Migration{migration(1), migration(2), migration(3)}); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(ran, []int{3}) {
+			t.Errorf("ran %v after resuming, want [3]", ran)
+		}
+	})
+}
+
+func TestRepository(t *testing.T) {
+	type value struct {
+		N int `json:"n"`
+	}
+	repository := NewRepository[value](NewMemoryStore(), "b")
+	repository.Put("g1/a", value{N: 1})
+	repository.Put("g1/b", value{N: 2})
+	repository.Put("g2/a", value{N: 3})
+
+	err := repository.Update("g1/a", func(v *value, exists bool) (bool, error) {
+		v.N += 10
+		return false, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = repository.Update("g1/b", func(v *value, exists bool) (bool, error) {
+		return true, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	records, err := repository.List("g1/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []Record[value]{{Key: "g1/a", Value: value{N: 11}}}; !reflect.DeepEqual(records, want) {
+		t.Errorf("List = %+v, want %+v", records, want)
+	}
+	if _, found, _ := repository.Get("g1/b"); found {
+		t.Error("g1/b was not removed")
+	}
+}
@@ -0,0 +1,41 @@
+package storage
+
+import "errors"
+
+// ErrClosed is returned by a Store used after Close.
+var ErrClosed = errors.New("storage is closed")
+
+// Store is an embedded key-value store whose keys are grouped in buckets.
+// Values are opaque bytes; Repository adds typed access on top.
+type Store interface {
+	Get(bucket string, key string) ([]byte, bool, error)
+	Put(bucket string, key string, value []byte) error
+	Delete(bucket string, key string) error
+	// Update atomically replaces the value of key with what fn returns.
+	// Returning a nil value deletes the key.
+	Update(bucket string, key string, fn func(value []byte, exists bool) ([]byte, error)) error
+	// Scan calls fn for every key of bucket starting with prefix, in key order.
+	Scan(bucket string, prefix string, fn func(key string, value []byte) error) error
+	Close() error
+}
+
+// Open opens the store at path, or an in-memory store when path is empty
+// or ":memory:", and brings it up to date with migrations.
+func Open(path string, migrations []Migration) (Store, error) {
+	var store Store
+	if path == "" || path == ":memory:" {
+		store = NewMemoryStore()
+	} else {
+		fileStore, err := OpenFileStore(path)
+		if err != nil {
+			return nil, err
+		}
+		store = fileStore
+	}
+
+	if err := Migrate(store, migrations); err != nil {
+		store.Close()
+		return nil, err
+	}
+	return store, nil
+}